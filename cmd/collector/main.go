@@ -2,16 +2,42 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"collector-fe-instrumentation/internal/adapter/clickhouse"
+	"collector-fe-instrumentation/internal/adapter/elasticsearch"
 	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/kafka"
 	"collector-fe-instrumentation/internal/adapter/loki"
+	"collector-fe-instrumentation/internal/adapter/metrics"
+	"collector-fe-instrumentation/internal/adapter/ndjson"
+	"collector-fe-instrumentation/internal/adapter/otlp"
+	"collector-fe-instrumentation/internal/adapter/remotewrite"
+	"collector-fe-instrumentation/internal/adapter/revocation"
+	"collector-fe-instrumentation/internal/adapter/tenant"
+	"collector-fe-instrumentation/internal/adapter/webhook"
 	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
 	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		os.Exit(runToken(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	slog.SetDefault(log)
 
@@ -20,15 +46,443 @@ func main() {
 		slog.Error("invalid config", "error", err)
 		os.Exit(1)
 	}
+	if cfg.HasSink(config.SinkFile) && cfg.FileSinkPath == config.FileSinkStdout {
+		// stdout carries the NDJSON items; keep the collector's own logs off it.
+		log = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		slog.SetDefault(log)
+	}
+	for _, w := range cfg.Warnings() {
+		slog.Warn("config", "warning", w)
+	}
+
+	// bg scopes the background loops (file watchers, remote-write); it is cancelled during shutdown.
+	bg, cancelBg := context.WithCancel(context.Background())
+	defer cancelBg()
+
+	svcOpts := []usecase.ServiceOption{usecase.WithLimits(domain.Limits{
+		MaxItemsPerType: cfg.MaxItemsPerType,
+		MaxStringLength: cfg.MaxStringLength,
+		MaxMapEntries:   cfg.MaxMapEntries,
+	})}
+	readiness := httpadapter.NewReadiness()
+	routerOpts := []httpadapter.RouterOption{httpadapter.WithLogger(log), httpadapter.WithReadiness(readiness)}
+
+	var tenants *tenant.Registry
+	if cfg.TenantsFile != "" {
+		var tenantOpts []tenant.Option
+		if cfg.HasSink(config.SinkLoki) && cfg.LokiURL == "" {
+			tenantOpts = append(tenantOpts, tenant.RequireLokiURL())
+		}
+		reg, err := tenant.Load(cfg.TenantsFile, log, tenantOpts...)
+		if err != nil {
+			slog.Error("load tenant registry", "error", err)
+			os.Exit(1)
+		}
+		tenants = reg
+		slog.Info("tenant registry loaded", "path", cfg.TenantsFile, "tenants", len(tenants.Names()))
+		go tenants.Watch(bg, cfg.TenantsReloadInterval)
+		readiness.AddCheck("config:tenants", tenants.Err)
+		routerOpts = append(routerOpts, httpadapter.WithTenantRegistry(tenants))
+	}
+
+	// RUM metrics live on the scrape registry when metrics are enabled, otherwise on a private one for remote-write.
+	rumRegistry := prometheus.NewRegistry()
+	var m *metrics.Metrics
+	var metricsSrv *http.Server
+	if cfg.MetricsEnabled {
+		metricsOpts := []metrics.Option{metrics.WithMaxTenants(cfg.MetricsMaxTenants)}
+		if tenants != nil {
+			metricsOpts = append(metricsOpts, metrics.WithTenants(tenants.Has))
+		}
+		m = metrics.New(metricsOpts...)
+		rumRegistry = m.Registry
+		svcOpts = append(svcOpts, usecase.WithRecorder(m))
+		routerOpts = append(routerOpts, httpadapter.WithMetrics(m))
+		metricsSrv = serveMetrics(":"+cfg.MetricsPort, m.Handler())
+	}
+	if cfg.RUMMetricsEnabled && (cfg.MetricsEnabled || cfg.RemoteWriteURL != "") {
+		svcOpts = append(svcOpts, usecase.WithObserver(metrics.NewRUM(rumRegistry, cfg.RUMMaxLabelValues, cfg.RUMMaxSeries)))
+	}
+	var flusher *remotewrite.Flusher
+	if cfg.RemoteWriteURL != "" {
+		rw := remotewrite.NewClient(cfg.RemoteWriteURL, cfg.RemoteWriteToken, cfg.RemoteWriteTimeout, cfg.RemoteWriteMaxRetries)
+		flusher = remotewrite.NewFlusher(rw, rumRegistry, "faro_rum_", cfg.RemoteWriteInterval, log)
+		go flusher.Run(bg)
+		slog.Info("remote write enabled", "url", cfg.RemoteWriteURL, "interval", cfg.RemoteWriteInterval)
+	}
+
+	var tracer *tracing.Tracer
+	if cfg.TracingEndpoint != "" {
+		exporter := otlp.NewTraceExporter(cfg.TracingEndpoint, cfg.TracingServiceName, cfg.TracingHeaders, cfg.TracingTimeout)
+		tracer = tracing.NewTracer(exporter, tracing.Options{SampleRatio: cfg.TracingSampleRatio, Log: log})
+		go tracer.Run(bg)
+		routerOpts = append(routerOpts, httpadapter.WithTracer(tracer))
+		slog.Info("tracing enabled", "endpoint", cfg.TracingEndpoint, "sample_ratio", cfg.TracingSampleRatio)
+	}
+
+	sinks, err := buildSink(cfg, m, tenants, log)
+	if err != nil {
+		slog.Error("configure sinks", "error", err)
+		os.Exit(1)
+	}
+	sinks.addChecks(readiness, cfg.ReadyQueuePercent)
+	if sinks.lokiTargets != nil && cfg.LokiProbeInterval > 0 {
+		prober := loki.NewProber(sinks.lokiTargets, cfg.LokiProbeInterval, log)
+		go prober.Run(bg)
+		readiness.AddCheck("loki", prober.Err)
+	}
+	svcOpts = append(svcOpts, usecase.WithSink(sinks.sink))
+	collectorSvc := usecase.NewCollectorService(nil, log, svcOpts...)
+	if cfg.RevocationFile != "" {
+		revocations, err := revocation.Load(cfg.RevocationFile, log)
+		if err != nil {
+			slog.Error("load revocation list", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("revocation list loaded", "path", cfg.RevocationFile, "entries", revocations.Len())
+		go revocations.Watch(bg, cfg.RevocationReloadInterval)
+		readiness.AddCheck("config:revocations", revocations.Err)
+		routerOpts = append(routerOpts, httpadapter.WithRevocationList(revocations))
+	}
+	router := httpadapter.Router(cfg, collectorSvc, routerOpts...)
+
+	srvOpts := httpadapter.ServerOptions{
+		Addr:             ":" + cfg.HTTPPort,
+		CertFile:         cfg.TLSCertFile,
+		KeyFile:          cfg.TLSKeyFile,
+		MinTLSVersion:    cfg.TLSMinVersion,
+		ACMEDomains:      cfg.ACMEDomains,
+		ACMEEmail:        cfg.ACMEEmail,
+		ACMEDirectoryURL: cfg.ACMEDirectoryURL,
+		ACMECAFile:       cfg.ACMECAFile,
+		ACMECacheDir:     cfg.ACMECacheDir,
+		Log:              log,
+	}
+	if cfg.HTTPRedirectPort != "" {
+		srvOpts.RedirectAddr = ":" + cfg.HTTPRedirectPort
+	}
+	srv, err := httpadapter.NewServer(router, srvOpts)
+	if err != nil {
+		slog.Error("configure server", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("listening", "addr", srvOpts.Addr, "tls", srv.TLS(), "acme_domains", cfg.ACMEDomains, "redirect_addr", srvOpts.RedirectAddr)
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
 
-	lokiClient := loki.NewClient(cfg.LokiURL, cfg.LokiToken, cfg.LokiTimeout)
-	collectorSvc := usecase.NewCollectorService(lokiClient, log)
-	router := httpadapter.Router(cfg, collectorSvc, httpadapter.WithLogger(log))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		stop()
+		if err != nil {
+			slog.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	case <-ctx.Done():
+	}
+	// A second signal kills the process with the default behaviour.
+	stop()
 
-	addr := ":" + cfg.HTTPPort
-	slog.Info("listening", "addr", addr)
-	if err := router.Run(addr); err != nil {
-		slog.Error("server failed", "error", err)
+	slog.Info("shutting down", "delay", cfg.ShutdownDelay, "grace_period", cfg.ShutdownGracePeriod)
+	if err := shutdown(srv, readiness, metricsSrv, sinks.close, flusher, tracer, cfg); err != nil {
+		cancelBg()
+		slog.Error("shutdown incomplete", "error", err)
 		os.Exit(1)
 	}
+	slog.Info("shutdown complete")
+}
+
+// shutdown fails readiness, drains in-flight requests, then flushes the sink queues, the sinks, remote-write
+// and the collector's own spans.
+// The whole sequence is bounded by ShutdownDelay + ShutdownGracePeriod.
+func shutdown(srv *httpadapter.Server, readiness *httpadapter.Readiness, metricsSrv *http.Server,
+	closeSinks func(context.Context) error, flusher *remotewrite.Flusher, tracer *tracing.Tracer, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownDelay+cfg.ShutdownGracePeriod)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Drain(ctx, readiness, cfg.ShutdownDelay); err != nil {
+		errs = append(errs, fmt.Errorf("drain requests: %w", err))
+	}
+	slog.Info("in-flight requests drained")
+	if err := closeSinks(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("flush sinks: %w", err))
+	}
+	if flusher != nil {
+		if err := flusher.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush remote write: %w", err))
+		}
+	}
+	if tracer != nil {
+		if err := tracer.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush traces: %w", err))
+		}
+	}
+	if metricsSrv != nil {
+		errs = append(errs, metricsSrv.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// buildSink creates the configured sinks; several sinks or SINK_ROUTES go through the fan-out router.
+// Every sink sits behind a circuit breaker; the set also keeps the raw sinks so shutdown can close them.
+func buildSink(cfg *config.Config, m *metrics.Metrics, tenants *tenant.Registry, log *slog.Logger) (*sinkSet, error) {
+	set := &sinkSet{breakers: make(map[string]*usecase.CircuitBreaker)}
+	sinks := make(map[string]usecase.Sink)
+	breakerOpts := make(map[string][]usecase.BreakerOption)
+	if cfg.HasSink(config.SinkLoki) {
+		transport, err := loki.NewTransport(loki.TransportOptions{
+			CAFile:              cfg.LokiCAFile,
+			CertFile:            cfg.LokiCertFile,
+			KeyFile:             cfg.LokiKeyFile,
+			ServerName:          cfg.LokiServerName,
+			MinVersion:          cfg.LokiTLSMinVersion,
+			ProxyURL:            cfg.LokiProxyURL,
+			MaxIdleConns:        cfg.LokiMaxIdleConns,
+			MaxIdleConnsPerHost: cfg.LokiMaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.LokiIdleConnTimeout,
+			KeepAlive:           cfg.LokiKeepAlive,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defaults := loki.Options{
+			URL:       cfg.LokiURL,
+			Auth:      loki.Auth{Token: cfg.LokiToken},
+			Timeout:   cfg.LokiTimeout,
+			Transport: transport,
+		}
+		var w usecase.LokiWriter = loki.New(defaults)
+		set.lokiTargets = func() []loki.Options { return []loki.Options{defaults} }
+		if tenants != nil {
+			resolve := loki.RegistryResolver(tenants, defaults)
+			w = loki.NewTenantWriter(resolve)
+			// One circuit per Loki endpoint: a tenant's own Loki failing must not stop the others.
+			breakerOpts[config.SinkLoki] = []usecase.BreakerOption{usecase.WithBreakerKey(func(tenantID string) string {
+				opts, _ := resolve(tenantID)
+				return opts.URL
+			})}
+			set.lokiTargets = func() []loki.Options {
+				var targets []loki.Options
+				for _, name := range tenants.Names() {
+					if opts, ok := resolve(name); ok {
+						targets = append(targets, opts)
+					}
+				}
+				return targets
+			}
+		}
+		if m != nil {
+			w = m.InstrumentLoki(w)
+		}
+		sinks[config.SinkLoki] = usecase.NewLokiSink(w)
+	}
+	if cfg.HasSink(config.SinkOTLP) {
+		sinks[config.SinkOTLP] = otlp.NewExporter(cfg.OTLPEndpoint, cfg.OTLPProtocol, cfg.OTLPHeaders, cfg.OTLPTimeout)
+		slog.Info("otlp sink enabled", "endpoint", cfg.OTLPEndpoint, "protocol", cfg.OTLPProtocol)
+	}
+	if cfg.HasSink(config.SinkFile) {
+		w := io.Writer(os.Stdout)
+		if cfg.FileSinkPath != config.FileSinkStdout {
+			f, err := ndjson.OpenRotating(cfg.FileSinkPath, ndjson.RotateOptions{
+				MaxBytes: cfg.FileSinkMaxBytes,
+				Interval: cfg.FileSinkRotateInterval,
+				MaxFiles: cfg.FileSinkMaxFiles,
+				Compress: cfg.FileSinkCompress,
+			}, log)
+			if err != nil {
+				return nil, err
+			}
+			w = f
+		}
+		sinks[config.SinkFile] = ndjson.New(w)
+		slog.Info("file sink enabled", "path", cfg.FileSinkPath)
+	}
+	if cfg.HasSink(config.SinkElasticsearch) {
+		sinks[config.SinkElasticsearch] = elasticsearch.NewClient(elasticsearch.Options{
+			URL:        cfg.ElasticsearchURL,
+			Username:   cfg.ElasticsearchUsername,
+			Password:   cfg.ElasticsearchPassword,
+			APIKey:     cfg.ElasticsearchAPIKey,
+			Index:      cfg.ElasticsearchIndex,
+			Apps:       cfg.ElasticsearchIndexApps,
+			Envs:       cfg.ElasticsearchIndexEnvs,
+			Timeout:    cfg.ElasticsearchTimeout,
+			MaxRetries: cfg.ElasticsearchMaxRetries,
+		})
+		slog.Info("elasticsearch sink enabled", "url", cfg.ElasticsearchURL, "index", cfg.ElasticsearchIndex)
+	}
+	if cfg.HasSink(config.SinkClickHouse) {
+		ch := clickhouse.NewClient(clickhouse.Options{
+			URL:      cfg.ClickHouseURL,
+			Database: cfg.ClickHouseDatabase,
+			Username: cfg.ClickHouseUsername,
+			Password: cfg.ClickHousePassword,
+			Timeout:  cfg.ClickHouseTimeout,
+		})
+		if cfg.ClickHouseCreateSchema {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.ClickHouseTimeout)
+			err := ch.EnsureSchema(ctx)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+		}
+		sinks[config.SinkClickHouse] = ch
+		slog.Info("clickhouse sink enabled", "url", cfg.ClickHouseURL, "database", cfg.ClickHouseDatabase)
+	}
+	if cfg.HasSink(config.SinkWebhook) {
+		var text string
+		if cfg.WebhookTemplateFile != "" {
+			b, err := os.ReadFile(cfg.WebhookTemplateFile)
+			if err != nil {
+				return nil, fmt.Errorf("read webhook template: %w", err)
+			}
+			text = string(b)
+		}
+		tmpl, err := webhook.ParseTemplate(cfg.WebhookFormat, text)
+		if err != nil {
+			return nil, err
+		}
+		wh, err := webhook.New(webhook.Options{
+			URL:                cfg.WebhookURL,
+			Template:           tmpl,
+			Secret:             cfg.WebhookSecret,
+			Window:             cfg.WebhookDedupWindow,
+			SpikeThreshold:     cfg.WebhookSpikeThreshold,
+			MaxAlertsPerMinute: cfg.WebhookMaxAlerts,
+			Timeout:            cfg.WebhookTimeout,
+			MaxRetries:         cfg.WebhookMaxRetries,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks[config.SinkWebhook] = wh
+		slog.Info("webhook sink enabled", "format", cfg.WebhookFormat, "dedup_window", cfg.WebhookDedupWindow)
+	}
+	if cfg.HasSink(config.SinkKafka) {
+		producer, err := kafka.NewProducer(kafka.Options{
+			Brokers:            cfg.KafkaBrokers,
+			Topic:              cfg.KafkaTopic,
+			Apps:               cfg.KafkaTopicApps,
+			AutoCreateTopics:   cfg.KafkaAutoCreateTopics,
+			Batch:              cfg.KafkaBatch,
+			Compression:        cfg.KafkaCompression,
+			Acks:               cfg.KafkaAcks,
+			MaxBufferedRecords: cfg.KafkaMaxBufferedRecords,
+			DeliveryTimeout:    cfg.KafkaDeliveryTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks[config.SinkKafka] = producer
+		slog.Info("kafka sink enabled", "brokers", cfg.KafkaBrokers, "topic", cfg.KafkaTopic)
+	}
+	set.sinks = sinks
+	guarded := make(map[string]usecase.Sink, len(sinks))
+	for name, sink := range sinks {
+		b := usecase.NewCircuitBreaker(sink, cfg.SinkBreakerFailures, cfg.SinkBreakerCooldown, breakerOpts[name]...)
+		set.breakers[name] = b
+		guarded[name] = b
+	}
+	if !cfg.Fanout() {
+		set.sink = guarded[cfg.Sinks[0]]
+		return set, nil
+	}
+
+	routes := make([]usecase.Route, 0, len(cfg.SinkRoutes))
+	for _, r := range cfg.SinkRoutes {
+		routes = append(routes, usecase.Route{Tenants: r.Tenants, Apps: r.Apps, Environments: r.Environments, Kinds: r.Kinds, Sinks: r.Sinks})
+	}
+	opts := []usecase.RouterOption{
+		usecase.WithQueueSize(cfg.SinkQueueSize),
+		usecase.WithQueueWorkers(cfg.SinkQueueWorkers),
+		usecase.WithSinkTimeout(cfg.SinkTimeout),
+		usecase.WithRouterLogger(log),
+	}
+	if m != nil {
+		opts = append(opts, usecase.WithRouterRecorder(m), usecase.WithQueueObserver(m))
+	}
+	slog.Info("sink fan-out enabled", "sinks", cfg.Sinks, "routes", len(routes))
+	router, err := usecase.NewSinkRouter(guarded, routes, opts...)
+	if err != nil {
+		return nil, err
+	}
+	set.sink, set.router = router, router
+	return set, nil
+}
+
+// sinkSet is what buildSink wires: the sink handed to the collector plus what shutdown and /readyz need.
+type sinkSet struct {
+	sink     usecase.Sink
+	router   *usecase.SinkRouter
+	sinks    map[string]usecase.Sink
+	breakers map[string]*usecase.CircuitBreaker
+	// lokiTargets lists the Loki endpoints to probe; nil without the loki sink.
+	lokiTargets func() []loki.Options
+}
+
+// close drains the router's queues first, since their workers write to the sinks, then closes
+// the sinks that buffer or hold files.
+func (s *sinkSet) close(ctx context.Context) error {
+	var errs []error
+	if s.router != nil {
+		if err := s.router.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("sink queues: %w", err))
+		}
+	}
+	for name, sink := range s.sinks {
+		var err error
+		switch c := sink.(type) {
+		case interface{ Close(context.Context) error }:
+			err = c.Close(ctx)
+		case io.Closer:
+			err = c.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// addChecks makes /readyz fail while a sink's circuits are all open or a queue is at least queuePercent
+// full. A half-open circuit counts as ready: only traffic can run the trial write that closes it.
+func (s *sinkSet) addChecks(r *httpadapter.Readiness, queuePercent int) {
+	for name, b := range s.breakers {
+		r.AddCheck("breaker:"+name, func() error {
+			if b.State() == usecase.BreakerOpen {
+				return errors.New("circuit open")
+			}
+			return nil
+		})
+	}
+	if s.router == nil {
+		return
+	}
+	limit := s.router.QueueCapacity() * queuePercent / 100
+	for _, name := range s.router.Sinks() {
+		r.AddCheck("queue:"+name, func() error {
+			if depth := s.router.QueueDepth(name); depth >= limit {
+				return fmt.Errorf("queue %d/%d items", depth, s.router.QueueCapacity())
+			}
+			return nil
+		})
+	}
+}
+
+// serveMetrics exposes /metrics on its own port so it can stay off the public listener.
+func serveMetrics(addr string, h http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	slog.Info("metrics listening", "addr", addr)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+	return srv
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/revocation"
+	"collector-fe-instrumentation/internal/auth"
+	"collector-fe-instrumentation/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const tokenUsage = `Usage:
+  collector token issue   [flags]           mint a collector token with the configured SECRET_KEY
+  collector token inspect [flags] <token>   decode a token and check it the way /collect would
+
+Run "collector token <command> -h" for the flags of each command.
+`
+
+// runToken implements the "token" subcommand and returns the process exit code.
+func runToken(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, tokenUsage)
+		return 2
+	}
+	cfg := config.Load()
+	switch args[0] {
+	case "issue":
+		return runTokenIssue(cfg, args[1:], stdout, stderr)
+	case "inspect":
+		return runTokenInspect(cfg, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown token command %q\n\n%s", args[0], tokenUsage)
+		return 2
+	}
+}
+
+func runTokenIssue(cfg *config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tenant := fs.String("tenant", "", "bind the token to this tenant (tenant claim)")
+	role := fs.String("role", "user", "role claim (admin or user)")
+	issuer := fs.String("issuer", cfg.JWTIssuer, "iss claim")
+	ttl := fs.Duration("ttl", 30*24*time.Hour, "lifetime of the token (exp claim); 0 omits exp")
+	origins := fs.String("origin", "", "comma-separated origins the token is bound to (origins claim)")
+	jti := fs.String("jti", "", "token ID (jti claim); random when empty")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if cfg.SecretKey == "" {
+		fmt.Fprintln(stderr, config.ErrMissingSecretKey)
+		return 1
+	}
+
+	token, err := auth.Issue(cfg.SecretKey, auth.IssueParams{
+		Tenant:  *tenant,
+		Role:    *role,
+		Issuer:  *issuer,
+		TTL:     *ttl,
+		Origins: splitList(*origins),
+		ID:      *jti,
+	}, time.Now())
+	if err != nil {
+		fmt.Fprintln(stderr, "issue token:", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, token)
+	return 0
+}
+
+func runTokenInspect(cfg *config.Config, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token inspect", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tenant := fs.String("tenant", "", "tenant of the simulated /collect request")
+	origin := fs.String("origin", "", "Origin header of the simulated request")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(stderr, tokenUsage)
+		return 2
+	}
+	tokenStr := strings.TrimSpace(fs.Arg(0))
+
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+	if err != nil {
+		fmt.Fprintln(stdout, "decode: FAIL:", err)
+		return 1
+	}
+	printJSON(stdout, "header", unverified.Header)
+	printJSON(stdout, "claims", unverified.Claims)
+	fmt.Fprintln(stdout, "revocation entry:", revocation.HashToken(tokenStr))
+
+	var revocations auth.RevocationChecker
+	if cfg.RevocationFile != "" {
+		list, err := revocation.Load(cfg.RevocationFile, nil)
+		if err != nil {
+			fmt.Fprintln(stderr, "load revocation list:", err)
+			return 1
+		}
+		revocations = list
+	}
+	validator := auth.NewValidator(cfg, revocations)
+	if claims, ok := unverified.Claims.(jwt.MapClaims); ok && !validator.BindsClaims() {
+		_, hasTenant := claims[auth.ClaimTenant]
+		_, hasOrigins := claims[auth.ClaimOrigins]
+		if hasTenant || hasOrigins {
+			fmt.Fprintln(stdout, "binding: tenant and origins claims are not enforced (JWT_ENFORCE_BINDING=false)")
+		}
+	}
+	_, err = validator.Validate(tokenStr, auth.Request{Tenant: *tenant, Origin: *origin})
+	if err != nil {
+		var authErr *auth.Error
+		if errors.As(err, &authErr) {
+			fmt.Fprintf(stdout, "verify: FAIL (%s): %s\n", authErr.Reason, authErr.Detail)
+		} else {
+			fmt.Fprintln(stdout, "verify: FAIL:", err)
+		}
+		return 1
+	}
+	fmt.Fprintln(stdout, "verify: OK, /collect would accept this token")
+	return 0
+}
+
+func printJSON(w io.Writer, label string, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", label, v)
+		return
+	}
+	fmt.Fprintf(w, "%s: %s\n", label, b)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(part); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
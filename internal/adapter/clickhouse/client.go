@@ -0,0 +1,254 @@
+// Package clickhouse inserts Faro items into typed ClickHouse tables over the HTTP interface.
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+//go:embed schema.sql
+var schemaSQL string
+
+const (
+	defaultTimeout  = 15 * time.Second
+	defaultDatabase = "faro"
+	timeFormat      = "2006-01-02 15:04:05.000"
+	// insertSettings make ClickHouse buffer the small per-request inserts server-side and flush them
+	// as one part, instead of creating a part per insert; the response still waits for the flush.
+	insertSettings = "&async_insert=1&wait_for_async_insert=1"
+)
+
+// Tables per item kind.
+var tables = map[string]string{
+	domain.KindLog:         "faro_logs",
+	domain.KindEvent:       "faro_events",
+	domain.KindMeasurement: "faro_measurements",
+	domain.KindException:   "faro_exceptions",
+}
+
+// metaColumns are the Meta.Fields keys stored in typed columns; the rest go to the meta map.
+var metaColumns = map[string]bool{
+	"app": true, "app_version": true, "environment": true, "browser_name": true, "browser_version": true,
+	"browser_os": true, "browser_mobile": true, "session_id": true, "page_url": true, "view_name": true,
+	"sdk_version": true, "user_username": true,
+}
+
+// Options configures the Client.
+type Options struct {
+	// URL is the ClickHouse HTTP endpoint (e.g. http://clickhouse:8123).
+	URL      string
+	Database string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// Client batches the items of each Write into one JSONEachRow insert per table, sent as an
+// asynchronous insert so ClickHouse merges them across requests.
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient creates a ClickHouse client.
+func NewClient(opts Options) *Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Database == "" {
+		opts.Database = defaultDatabase
+	}
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+	return &Client{opts: opts, httpClient: &http.Client{Timeout: opts.Timeout}, now: time.Now}
+}
+
+// Schema returns the bundled DDL statements for database.
+func Schema(database string) []string {
+	var sql strings.Builder
+	for _, l := range strings.Split(strings.ReplaceAll(schemaSQL, "{database}", database), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(l), "--") {
+			sql.WriteString(l + "\n")
+		}
+	}
+	var stmts []string
+	for _, s := range strings.Split(sql.String(), ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// EnsureSchema creates the database and tables if they do not exist.
+func (c *Client) EnsureSchema(ctx context.Context) error {
+	for _, stmt := range Schema(c.opts.Database) {
+		if err := c.exec(ctx, stmt, nil); err != nil {
+			return fmt.Errorf("apply schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// Write implements usecase.Sink.
+func (c *Client) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := c.now().UTC()
+	bodies := make(map[string]*bytes.Buffer)
+	var order []string
+	for _, it := range items {
+		table, ok := tables[it.Kind]
+		if !ok {
+			continue
+		}
+		b, ok := bodies[table]
+		if !ok {
+			b = &bytes.Buffer{}
+			bodies[table] = b
+			order = append(order, table)
+		}
+		row, err := json.Marshal(toRow(tenantID, it, now))
+		if err != nil {
+			return fmt.Errorf("marshal row: %w", err)
+		}
+		b.Write(row)
+		b.WriteByte('\n')
+	}
+
+	var errs []error
+	for _, table := range order {
+		query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", c.opts.Database, table)
+		if err := c.exec(ctx, query, bodies[table]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", table, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) exec(ctx context.Context, query string, body io.Reader) error {
+	u := c.opts.URL + "/?query=" + url.QueryEscape(query) + insertSettings
+	if body == nil {
+		body, u = strings.NewReader(query), c.opts.URL+"/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if c.opts.Username != "" {
+		req.Header.Set("X-ClickHouse-User", c.opts.Username)
+		req.Header.Set("X-ClickHouse-Key", c.opts.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode/100 == 4 {
+			return fmt.Errorf("%w: clickhouse returned %d: %s", domain.ErrSinkRejected, resp.StatusCode, strings.TrimSpace(string(b)))
+		}
+		return fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// toRow maps an item to the columns of its table.
+func toRow(tenantID string, it domain.Item, now time.Time) map[string]interface{} {
+	ts := now
+	if t, err := time.Parse(time.RFC3339Nano, it.Timestamp); err == nil {
+		ts = t.UTC()
+	}
+	m := it.Meta
+	if m == nil {
+		m = &domain.Meta{}
+	}
+	extra := make(map[string]string)
+	for k, v := range m.Fields() {
+		if !metaColumns[k] {
+			extra[k] = stringify(v)
+		}
+	}
+	row := map[string]interface{}{
+		"timestamp":       ts.Format(timeFormat),
+		"received_at":     now.Format(timeFormat),
+		"tenant":          tenantID,
+		"app":             m.App.Name,
+		"app_version":     m.App.Version,
+		"environment":     m.App.Environment,
+		"browser_name":    m.Browser.Name,
+		"browser_version": m.Browser.Version,
+		"browser_os":      m.Browser.OS,
+		"browser_mobile":  m.Browser.Mobile,
+		"session_id":      m.Session.ID,
+		"page_url":        m.Page.URL,
+		"view_name":       m.View.Name,
+		"sdk_version":     m.SDK.Version,
+		"user_username":   m.User.Username,
+		"meta":            extra,
+	}
+
+	str := func(k string) string { return stringify(it.Fields[k]) }
+	switch it.Kind {
+	case domain.KindLog:
+		row["level"] = str("level")
+		row["message"] = str("message")
+	case domain.KindEvent:
+		row["name"] = str("event_name")
+		row["domain"] = str("event_domain")
+		attrs := make(map[string]string)
+		for k, v := range it.Fields {
+			if name, ok := strings.CutPrefix(k, "event_data_"); ok {
+				attrs[name] = stringify(v)
+			}
+		}
+		row["attributes"] = attrs
+	case domain.KindMeasurement:
+		row["type"] = str("measurement_type")
+		values := make(map[string]float64)
+		for k, v := range it.Fields {
+			if name, ok := strings.CutPrefix(k, "measurement_value_"); ok {
+				if f, ok := v.(float64); ok {
+					values[name] = f
+				}
+			}
+		}
+		row["values"] = values
+	case domain.KindException:
+		row["type"] = str("exception_type")
+		row["value"] = str("exception_value")
+		row["stacktrace"] = str("exception_stacktrace")
+	}
+	return row
+}
+
+// stringify renders scalars as text and anything else as JSON.
+func stringify(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool, float64, int, int64:
+		return fmt.Sprint(val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,108 @@
+-- Faro collector schema for ClickHouse. {database} is replaced by CLICKHOUSE_DATABASE.
+-- Every table shares the meta columns; the last columns are specific to the item kind.
+-- Tables are partitioned by received_at: timestamp comes from the browser clock, which could
+-- otherwise create partitions for arbitrary past or future months.
+
+CREATE DATABASE IF NOT EXISTS {database};
+
+CREATE TABLE IF NOT EXISTS {database}.faro_logs
+(
+    timestamp       DateTime64(3, 'UTC'),
+    received_at     DateTime64(3, 'UTC'),
+    tenant          LowCardinality(String),
+    app             LowCardinality(String),
+    app_version     LowCardinality(String),
+    environment     LowCardinality(String),
+    browser_name    LowCardinality(String),
+    browser_version LowCardinality(String),
+    browser_os      LowCardinality(String),
+    browser_mobile  Bool,
+    session_id      String,
+    page_url        String,
+    view_name       LowCardinality(String),
+    sdk_version     LowCardinality(String),
+    user_username   String,
+    meta            Map(String, String),
+    level           LowCardinality(String),
+    message         String
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(received_at)
+ORDER BY (tenant, app, level, timestamp);
+
+CREATE TABLE IF NOT EXISTS {database}.faro_events
+(
+    timestamp       DateTime64(3, 'UTC'),
+    received_at     DateTime64(3, 'UTC'),
+    tenant          LowCardinality(String),
+    app             LowCardinality(String),
+    app_version     LowCardinality(String),
+    environment     LowCardinality(String),
+    browser_name    LowCardinality(String),
+    browser_version LowCardinality(String),
+    browser_os      LowCardinality(String),
+    browser_mobile  Bool,
+    session_id      String,
+    page_url        String,
+    view_name       LowCardinality(String),
+    sdk_version     LowCardinality(String),
+    user_username   String,
+    meta            Map(String, String),
+    name            LowCardinality(String),
+    domain          LowCardinality(String),
+    attributes      Map(String, String)
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(received_at)
+ORDER BY (tenant, app, name, timestamp);
+
+CREATE TABLE IF NOT EXISTS {database}.faro_measurements
+(
+    timestamp       DateTime64(3, 'UTC'),
+    received_at     DateTime64(3, 'UTC'),
+    tenant          LowCardinality(String),
+    app             LowCardinality(String),
+    app_version     LowCardinality(String),
+    environment     LowCardinality(String),
+    browser_name    LowCardinality(String),
+    browser_version LowCardinality(String),
+    browser_os      LowCardinality(String),
+    browser_mobile  Bool,
+    session_id      String,
+    page_url        String,
+    view_name       LowCardinality(String),
+    sdk_version     LowCardinality(String),
+    user_username   String,
+    meta            Map(String, String),
+    type            LowCardinality(String),
+    values          Map(LowCardinality(String), Float64)
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(received_at)
+ORDER BY (tenant, app, type, view_name, timestamp);
+
+CREATE TABLE IF NOT EXISTS {database}.faro_exceptions
+(
+    timestamp       DateTime64(3, 'UTC'),
+    received_at     DateTime64(3, 'UTC'),
+    tenant          LowCardinality(String),
+    app             LowCardinality(String),
+    app_version     LowCardinality(String),
+    environment     LowCardinality(String),
+    browser_name    LowCardinality(String),
+    browser_version LowCardinality(String),
+    browser_os      LowCardinality(String),
+    browser_mobile  Bool,
+    session_id      String,
+    page_url        String,
+    view_name       LowCardinality(String),
+    sdk_version     LowCardinality(String),
+    user_username   String,
+    meta            Map(String, String),
+    type            String,
+    value           String,
+    stacktrace      String
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(received_at)
+ORDER BY (tenant, app, type, timestamp);
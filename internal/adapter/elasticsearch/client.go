@@ -0,0 +1,280 @@
+// Package elasticsearch indexes Faro items with the Elasticsearch/OpenSearch _bulk API.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+const (
+	defaultTimeout   = 15 * time.Second
+	defaultIndex     = "faro-{tenant}-{date}"
+	defaultBaseDelay = 500 * time.Millisecond
+	maxDelay         = 10 * time.Second
+	bulkPath         = "/_bulk"
+	// otherPart replaces {app} and {env} values missing from Options.Apps and Options.Envs.
+	otherPart = "other"
+)
+
+// Options configures the Client. Username/Password (basic auth) and APIKey are mutually exclusive.
+type Options struct {
+	URL      string
+	Username string
+	Password string
+	APIKey   string
+	// Index is the target index pattern; {tenant}, {app}, {env}, {kind} and {date} (YYYY.MM.DD, UTC, of
+	// the time the collector received the items) are replaced per item. Default "faro-{tenant}-{date}".
+	Index string
+	// Apps and Envs list the values {app} and {env} may expand to; any other value indexes into "other".
+	Apps       []string
+	Envs       []string
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// Client writes items through _bulk, retrying only the items rejected with 429 or 5xx.
+type Client struct {
+	url        string
+	opts       Options
+	apps, envs map[string]bool
+	baseDelay  time.Duration
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient creates a bulk client for opts.URL (e.g. https://opensearch:9200).
+func NewClient(opts Options) *Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Index == "" {
+		opts.Index = defaultIndex
+	}
+	return &Client{
+		url:        strings.TrimSuffix(opts.URL, "/") + bulkPath,
+		opts:       opts,
+		apps:       allowlist(opts.Apps),
+		envs:       allowlist(opts.Envs),
+		baseDelay:  defaultBaseDelay,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		now:        time.Now,
+	}
+}
+
+// document is the indexed representation of an item.
+type document struct {
+	Timestamp string                 `json:"@timestamp"`
+	Tenant    string                 `json:"tenant"`
+	Kind      string                 `json:"kind"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+type bulkEntry struct {
+	index string
+	doc   []byte
+}
+
+// Write implements usecase.Sink.
+func (c *Client) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := c.now()
+	pending := make([]bulkEntry, 0, len(items))
+	for _, it := range items {
+		ts := now
+		if t, err := time.Parse(time.RFC3339Nano, it.Timestamp); err == nil {
+			ts = t
+		}
+		doc, err := json.Marshal(document{
+			Timestamp: ts.UTC().Format(time.RFC3339Nano),
+			Tenant:    tenantID,
+			Kind:      it.Kind,
+			Level:     it.Level(),
+			Message:   it.Message(),
+			Fields:    it.AllFields(),
+		})
+		if err != nil {
+			return fmt.Errorf("marshal document: %w", err)
+		}
+		// The index date uses the time received: client clocks would otherwise pick arbitrary old or future indices.
+		pending = append(pending, bulkEntry{index: c.indexName(tenantID, it, now), doc: doc})
+	}
+
+	var failed, rejected []error
+	for attempt := 0; ; attempt++ {
+		retry, itemsRejected, err := c.bulk(ctx, pending)
+		rejected = append(rejected, itemsRejected...)
+		if len(retry) == 0 {
+			if err != nil {
+				failed = append(failed, err)
+			}
+			break
+		}
+		if attempt >= c.opts.MaxRetries {
+			if err != nil {
+				failed = append(failed, err)
+			}
+			failed = append(failed, fmt.Errorf("%d items not indexed after %d retries", len(retry), attempt))
+			break
+		}
+		delay := c.baseDelay << attempt
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(append(append(failed, rejected...), ctx.Err())...)
+		case <-time.After(delay):
+		}
+		pending = retry
+	}
+	// Documents the cluster refused are domain.ErrSinkRejected, unless other items failed too: the
+	// circuit breaker must still see those.
+	switch {
+	case len(failed) > 0:
+		return fmt.Errorf("bulk: %w", errors.Join(append(failed, rejected...)...))
+	case len(rejected) > 0:
+		return fmt.Errorf("bulk: %w: %w", domain.ErrSinkRejected, errors.Join(rejected...))
+	}
+	return nil
+}
+
+// bulk sends entries once. It returns the entries worth retrying (whole request on network
+// errors, 429 and 5xx; otherwise items rejected with 429 or 5xx) and errors for rejected items.
+func (c *Client) bulk(ctx context.Context, entries []bulkEntry) (retry []bulkEntry, rejected []error, err error) {
+	var body bytes.Buffer
+	for _, e := range entries {
+		action, _ := json.Marshal(map[string]map[string]string{"create": {"_index": e.index}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(e.doc)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case c.opts.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.opts.APIKey)
+	case c.opts.Username != "":
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return entries, nil, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return entries, nil, fmt.Errorf("bulk returned %d: %s", resp.StatusCode, string(b))
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("%w: bulk returned %d: %s", domain.ErrSinkRejected, resp.StatusCode, string(b))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	if len(result.Items) != len(entries) {
+		return nil, nil, fmt.Errorf("bulk response has %d items for %d documents", len(result.Items), len(entries))
+	}
+	for i, item := range result.Items {
+		for _, r := range item {
+			switch {
+			case r.Status/100 == 2:
+			case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+				retry = append(retry, entries[i])
+			default:
+				rejected = append(rejected, fmt.Errorf("%s: status %d: %s", entries[i].index, r.Status, r.Error))
+			}
+		}
+	}
+	return retry, rejected, nil
+}
+
+func (c *Client) indexName(tenantID string, it domain.Item, received time.Time) string {
+	app, env := otherPart, otherPart
+	if it.Meta != nil {
+		if c.apps[it.Meta.App.Name] {
+			app = clientIndexPart(it.Meta.App.Name)
+		}
+		if c.envs[it.Meta.App.Environment] {
+			env = clientIndexPart(it.Meta.App.Environment)
+		}
+	}
+	return strings.NewReplacer(
+		"{tenant}", indexPart(tenantID),
+		"{app}", app,
+		"{env}", env,
+		"{kind}", it.Kind,
+		"{date}", received.UTC().Format("2006.01.02"),
+	).Replace(c.opts.Index)
+}
+
+func allowlist(values []string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+// maxClientIndexPart bounds {app} and {env} within the 255-byte index name limit.
+const maxClientIndexPart = 64
+
+// clientIndexPart is indexPart for {app} and {env}: dots become underscores so they cannot
+// form "." or ".." or hidden indices, leading "-", "_" and "+" are dropped, and the result is truncated.
+func clientIndexPart(v string) string {
+	v = strings.TrimLeft(strings.ReplaceAll(indexPart(v), ".", "_"), "-_+")
+	if len(v) > maxClientIndexPart {
+		v = v[:maxClientIndexPart]
+	}
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
+// indexPart lowercases v and replaces characters not allowed in index names.
+func indexPart(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(v) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
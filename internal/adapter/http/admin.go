@@ -0,0 +1,63 @@
+package http
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevocationList is a RevocationChecker that can be reloaded on demand.
+type RevocationList interface {
+	RevocationChecker
+	Reload() (int, error)
+}
+
+// AdminAuth returns a Gin middleware that requires "Authorization: Bearer <adminToken>".
+func AdminAuth(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, adminToken) {
+			logAuth(c, "admin: invalid credentials")
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request carries "Authorization: Bearer <adminToken>"; an empty adminToken matches nothing.
+func isAdmin(c *gin.Context, adminToken string) bool {
+	if adminToken == "" {
+		return false
+	}
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(adminToken)) == 1
+}
+
+// AdminHandler serves operational endpoints under /admin.
+type AdminHandler struct {
+	revocations RevocationList
+	log         *slog.Logger
+}
+
+// NewAdminHandler creates the handler for /admin endpoints.
+func NewAdminHandler(revocations RevocationList, log *slog.Logger) *AdminHandler {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &AdminHandler{revocations: revocations, log: log}
+}
+
+// ReloadRevocations is the POST /admin/revocations/reload handler.
+func (h *AdminHandler) ReloadRevocations(c *gin.Context) {
+	n, err := h.revocations.Reload()
+	if err != nil {
+		h.log.Error("admin: revocation reload failed", "error", err, "request_id", requestID(c))
+		abortWithError(c, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	h.log.Info("admin: revocation list reloaded", "entries", n)
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "entries": n})
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	queryToken = "token"
+
+	// ctxKeyBeacon marks requests served by the /beacon routes.
+	ctxKeyBeacon = "collector.beacon"
+)
+
+// BeaconMode returns a Gin middleware for navigator.sendBeacon requests.
+// Beacons are sent as text/plain without custom headers (no CORS preflight) and the browser
+// never reads the response, so every outcome is answered with 204 and only logged.
+// The token may come from the :token path param or the ?token= query string.
+func BeaconMode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ctxKeyBeacon, true)
+		c.Next()
+	}
+}
+
+func isBeacon(c *gin.Context) bool {
+	return c.GetBool(ctxKeyBeacon)
+}
+
+// respondOK writes the success response for the collect and beacon routes.
+func respondOK(c *gin.Context) {
+	if isBeacon(c) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
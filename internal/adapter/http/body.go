@@ -0,0 +1,76 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitBody returns a Gin middleware that caps the request body and transparently
+// decompresses gzip, deflate and br bodies. maxBytes bounds the bytes on the wire and
+// maxDecompressedBytes the bytes after decompression (zip-bomb guard); 0 is unlimited.
+// Overflow surfaces as *http.MaxBytesError when the handler reads the body.
+func LimitBody(maxBytes, maxDecompressedBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := c.Request.Body
+		if maxBytes > 0 {
+			body = http.MaxBytesReader(c.Writer, body, maxBytes)
+		}
+
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding != "" && encoding != "identity" {
+			decoded, err := decompress(encoding, body)
+			if err == errUnsupportedEncoding {
+				logAuth(c, "unsupported content encoding", "encoding", encoding)
+				abortWithError(c, http.StatusUnsupportedMediaType, CodeUnsupportedEncoding, "Unsupported Content-Encoding")
+				return
+			}
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid compressed body")
+				return
+			}
+			body = decoded
+			if maxDecompressedBytes > 0 {
+				body = http.MaxBytesReader(c.Writer, body, maxDecompressedBytes)
+			}
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.Header.Del("Content-Length")
+			c.Request.ContentLength = -1
+		}
+		c.Request.Body = body
+		c.Next()
+	}
+}
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// decompress wraps body in a decoder for encoding. "deflate" is zlib-wrapped per RFC 9110,
+// but raw DEFLATE streams (sent by some clients) are accepted too.
+func decompress(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		br := bufio.NewReader(body)
+		if hdr, err := br.Peek(2); err == nil && isZlibHeader(hdr) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is the stable, machine-readable identifier returned in error responses.
+// Public messages stay generic; the specific reason only goes to the server log.
+type ErrorCode string
+
+const (
+	CodeTokenMissing        ErrorCode = "token_missing"
+	CodeTokenInvalid        ErrorCode = "token_invalid"
+	CodeTokenExpired        ErrorCode = "token_expired"
+	CodeForbidden           ErrorCode = "forbidden"
+	CodeUnauthorized        ErrorCode = "unauthorized"
+	CodeInvalidRequest      ErrorCode = "invalid_request"
+	CodeInvalidJSON         ErrorCode = "invalid_json"
+	CodeEmptyPayload        ErrorCode = "empty_payload"
+	CodeRateLimited         ErrorCode = "rate_limited"
+	CodePayloadTooLarge     ErrorCode = "payload_too_large"
+	CodeUnsupportedEncoding ErrorCode = "unsupported_encoding"
+	CodeQuotaExceeded       ErrorCode = "quota_exceeded"
+	CodeUnavailable         ErrorCode = "unavailable"
+	CodeInternal            ErrorCode = "internal_error"
+)
+
+const (
+	headerRequestID = "X-Request-ID"
+
+	// ctxKeyRequestID is the gin context key holding the correlation ID of the request.
+	ctxKeyRequestID = "collector.request_id"
+)
+
+var requestIDRe = regexp.MustCompile(`^[\w\-.]{1,128}$`)
+
+// ErrorResponse is the JSON body of every error returned by the collector.
+type ErrorResponse struct {
+	Error     string    `json:"error"`
+	Code      ErrorCode `json:"code"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// abortWithError writes the error response and stops the handler chain.
+// Beacon requests always get an empty 204 (see BeaconMode); the caller has already logged the cause.
+func abortWithError(c *gin.Context, status int, code ErrorCode, message string) {
+	if isBeacon(c) {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID(c),
+	})
+}
+
+// RequestID returns a Gin middleware that assigns a correlation ID to each request.
+// A well-formed incoming X-Request-ID (e.g. from a proxy) is kept; otherwise a random one is generated.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(headerRequestID)
+		if !requestIDRe.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(ctxKeyRequestID, id)
+		c.Header(headerRequestID, id)
+		c.Next()
+	}
+}
+
+func requestID(c *gin.Context) string {
+	return c.GetString(ctxKeyRequestID)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
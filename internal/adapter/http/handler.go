@@ -1,11 +1,16 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"regexp"
 
 	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/ratelimit"
+	"collector-fe-instrumentation/internal/tracing"
 	"collector-fe-instrumentation/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -15,11 +20,13 @@ var tenantTokenRe = regexp.MustCompile(`^[\w\s\-.@]+$`)
 
 // CollectorHandler handles Faro collect endpoint.
 type CollectorHandler struct {
-	svc *usecase.CollectorService
-	log *slog.Logger
+	svc     *usecase.CollectorService
+	log     *slog.Logger
+	limiter *ratelimit.Limiter // nil when rate limiting is off
+	metrics MetricsRecorder    // nil when metrics are off
 }
 
-// NewCollectorHandler creates the HTTP handler for /collect/:tenant/:token.
+// NewCollectorHandler creates the HTTP handler for /collect/:tenant and /collect/:tenant/:token.
 func NewCollectorHandler(svc *usecase.CollectorService, log *slog.Logger) *CollectorHandler {
 	if log == nil {
 		log = slog.Default()
@@ -27,39 +34,80 @@ func NewCollectorHandler(svc *usecase.CollectorService, log *slog.Logger) *Colle
 	return &CollectorHandler{svc: svc, log: log}
 }
 
-// Collect is the POST /collect/:tenant/:token handler.
+// Collect is the POST /collect/:tenant[/:token] and /beacon/:tenant[/:token] handler. The token was already checked by JWTAuth.
 func (h *CollectorHandler) Collect(c *gin.Context) {
 	tenantID := sanitizeParam(c.Param("tenant"))
-	token := sanitizeParam(c.Param("token"))
+	token := c.GetString(ctxKeyToken)
 	if tenantID == "" || token == "" {
-		h.log.Warn("collect: missing tenant or token")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tenant or token not provided"})
+		h.log.Warn("collect: missing tenant or token", "request_id", requestID(c))
+		abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Tenant or token not provided")
 		return
 	}
 
+	// The body is already capped and decompressed by LimitBody. Content-Type is not checked:
+	// Faro sends application/json, sendBeacon sends text/plain;charset=UTF-8; both carry JSON.
+	counted := &countingReader{r: c.Request.Body}
 	var payload domain.Payload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		h.log.Warn("collect: invalid JSON", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+	_, span := tracing.Start(c.Request.Context(), "payload.decode", tracing.KindInternal)
+	err := json.NewDecoder(counted).Decode(&payload)
+	span.SetAttributes(tracing.Int("payload.bytes", int(counted.n)), tracing.Int("payload.items", payload.ItemCount()))
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.log.Warn("collect: body too large", "limit", tooLarge.Limit, "tenant", tenantID, "request_id", requestID(c))
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Payload too large")
+			return
+		}
+		h.log.Warn("collect: invalid JSON", "error", err, "request_id", requestID(c))
+		abortWithError(c, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
 		return
 	}
 
+	if h.metrics != nil {
+		h.metrics.ObserveBodySize(c.FullPath(), counted.n)
+	}
+
+	if h.limiter != nil {
+		if d := h.limiter.AllowUsage(rateLimitKeys(c), payload.ItemCount(), int(counted.n)); !d.Allowed {
+			if h.metrics != nil {
+				h.metrics.ItemsDropped(tenantID, dropReason(d), payload.ItemCount())
+			}
+			abortRateLimited(c, d)
+			return
+		}
+	}
+
 	if err := h.svc.Collect(c.Request.Context(), tenantID, &payload); err != nil {
 		switch {
 		case err == domain.ErrInvalidPayload || err == domain.ErrMissingTenant:
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		case errors.Is(err, domain.ErrLimitExceeded):
+			h.log.Warn("collect: payload over limits", "error", err, "tenant", tenantID, "request_id", requestID(c))
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Payload too large")
 			return
 		case err == domain.ErrEmptyPayload:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload, no data found"})
+			abortWithError(c, http.StatusBadRequest, CodeEmptyPayload, "Invalid payload, no data found")
+			return
+		case errors.Is(err, domain.ErrUnknownTenant):
+			h.log.Warn("collect: unknown tenant", "tenant", tenantID, "request_id", requestID(c))
+			abortWithError(c, http.StatusForbidden, CodeForbidden, "Access denied")
+			return
+		case errors.Is(err, usecase.ErrQueueFull), errors.Is(err, usecase.ErrCircuitOpen):
+			h.log.Warn("collect: sink unavailable", "error", err, "tenant", tenantID, "request_id", requestID(c))
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusServiceUnavailable, CodeUnavailable, "Service busy, retry later")
 			return
 		default:
-			h.log.Error("collect: loki push failed", "error", err, "tenant", tenantID)
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "failure"})
+			h.log.Error("collect: sink write failed", "error", err, "tenant", tenantID, "request_id", requestID(c))
+			abortWithError(c, http.StatusInternalServerError, CodeInternal, "Internal error")
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	respondOK(c)
 }
 
 func sanitizeParam(s string) string {
@@ -81,3 +129,15 @@ func trimSpace(s string) string {
 	}
 	return s[start:end]
 }
+
+// countingReader counts the bytes read through it (for byte-rate limits).
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
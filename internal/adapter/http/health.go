@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check reports the state of one component; a non-nil error fails readiness.
+// Checks run on every /readyz request, so they must only read state kept up to date elsewhere.
+type Check func() error
+
+// Readiness tracks whether the collector should receive new traffic. It fails for good once shutdown begins,
+// so load balancers stop routing here while in-flight requests still complete, and while any check fails.
+type Readiness struct {
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type componentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readyResponse struct {
+	Status string                     `json:"status"`
+	Checks map[string]componentStatus `json:"checks,omitempty"`
+}
+
+// NewReadiness returns a Readiness that reports ready.
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// AddCheck registers a component checked by /readyz.
+func (r *Readiness) AddCheck(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining makes /readyz fail from now on.
+func (r *Readiness) SetDraining() {
+	r.draining.Store(true)
+}
+
+// Draining reports whether shutdown has begun.
+func (r *Readiness) Draining() bool {
+	return r.draining.Load()
+}
+
+// readyz returns the GET /readyz handler. ?verbose adds the status of every component, which names
+// sink hosts and their errors, so it is honoured only with "Authorization: Bearer <adminToken>".
+func (r *Readiness) readyz(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r.serve(c, isAdmin(c, adminToken))
+	}
+}
+
+func (r *Readiness) serve(c *gin.Context, allowVerbose bool) {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	resp := readyResponse{Status: "ok"}
+	statuses := make(map[string]componentStatus, len(checks)+1)
+	for _, nc := range checks {
+		if err := nc.check(); err != nil {
+			resp.Status = "fail"
+			statuses[nc.name] = componentStatus{Status: "fail", Error: err.Error()}
+			continue
+		}
+		statuses[nc.name] = componentStatus{Status: "ok"}
+	}
+	if r.Draining() {
+		resp.Status = "draining"
+		statuses["shutdown"] = componentStatus{Status: "fail", Error: "draining"}
+	}
+	if _, verbose := c.GetQuery("verbose"); verbose && allowVerbose {
+		resp.Checks = statuses
+	}
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+// livez is the GET /livez handler: the process is up and serving requests.
+func livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsRecorder receives the HTTP adapter's measurements (implemented by the metrics adapter).
+type MetricsRecorder interface {
+	ObserveRequest(route, method string, status int, d time.Duration)
+	ObserveBodySize(route string, bytes int64)
+	AuthFailure(reason string)
+	ItemsDropped(tenant, reason string, n int)
+}
+
+// Instrument returns a Gin middleware that records every request by route and status.
+func Instrument(rec MetricsRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		rec.ObserveRequest(c.FullPath(), c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
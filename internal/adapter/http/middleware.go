@@ -1,124 +1,154 @@
 package http
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
-	"time"
 
+	"collector-fe-instrumentation/internal/auth"
 	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/tracing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
-	paramToken = "token"
+	paramToken   = "token"
+	headerAPIKey = "X-Faro-Api-Key"
+
+	// ctxKeyToken is the gin context key holding the raw token accepted by JWTAuth.
+	ctxKeyToken = "collector.token"
 )
 
-// JWTAuth returns a Gin middleware that validates JWT from URL param :token.
-func JWTAuth(cfg *config.Config) gin.HandlerFunc {
-	secretKey := []byte(cfg.SecretKey)
-	validateExp := cfg.JWTValidateExp
-	expectedIssuer := cfg.JWTIssuer
-	opts := []jwt.ParserOption{}
-	if !validateExp {
-		opts = append(opts, jwt.WithoutClaimsValidation())
+// RevocationChecker reports whether a token has been revoked, by jti or by the raw token.
+type RevocationChecker = auth.RevocationChecker
+
+// AuthOption configures JWTAuth.
+type AuthOption func(*authOptions)
+
+type authOptions struct {
+	revocations RevocationChecker
+	metrics     MetricsRecorder
+}
+
+// WithRevocationChecker rejects tokens reported as revoked by rc.
+func WithRevocationChecker(rc RevocationChecker) AuthOption {
+	return func(o *authOptions) {
+		o.revocations = rc
 	}
-	parser := jwt.NewParser(opts...)
+}
 
-	return func(c *gin.Context) {
-		tokenStr := c.Param(paramToken)
-		if tokenStr == "" {
-			logAuth(c, "token missing")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token missing"})
-			c.Abort()
-			return
-		}
+// WithAuthMetrics counts rejected tokens by reason.
+func WithAuthMetrics(rec MetricsRecorder) AuthOption {
+	return func(o *authOptions) {
+		o.metrics = rec
+	}
+}
+
+// JWTAuth returns a Gin middleware that validates the collector JWT.
+// The token is read from "Authorization: Bearer", then X-Faro-Api-Key, then the :token URL param
+// (when cfg.AllowPathToken is set) or, on beacon routes, ?token= (when cfg.BeaconQueryToken is set).
+// The checks live in auth.Validator.
+func JWTAuth(cfg *config.Config, opts ...AuthOption) gin.HandlerFunc {
+	o := authOptions{}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	allowPathToken, allowQueryToken := cfg.AllowPathToken, cfg.BeaconQueryToken
+	validator := auth.NewValidator(cfg, o.revocations)
 
-		token, err := parser.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(t *jwt.Token) (interface{}, error) {
-			return secretKey, nil
+	return func(c *gin.Context) {
+		tokenStr := extractToken(c, allowPathToken, allowQueryToken)
+		_, span := tracing.Start(c.Request.Context(), "auth.validate", tracing.KindInternal)
+		_, err := validator.Validate(tokenStr, auth.Request{
+			Tenant: sanitizeParam(c.Param("tenant")),
+			Origin: c.GetHeader("Origin"),
 		})
+		span.RecordError(err)
+		span.End()
 		if err != nil {
-			logAuth(c, fmt.Sprintf("invalid token: %v", err))
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "details": err.Error()})
-			c.Abort()
-			return
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			logAuth(c, "invalid token claims")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		if !validateRole(c, claims, expectedIssuer) {
-			return
-		}
-		if validateExp && !validateExpClaim(c, claims) {
+			var authErr *auth.Error
+			if !errors.As(err, &authErr) {
+				authErr = &auth.Error{Reason: auth.ReasonInvalid, Detail: err.Error()}
+			}
+			logAuth(c, authErr.Error(), "reason", string(authErr.Reason))
+			if o.metrics != nil {
+				o.metrics.AuthFailure(string(authErr.Reason))
+			}
+			status, code, message := authErrorResponse(authErr)
+			abortWithError(c, status, code, message)
 			return
 		}
+		c.Set(ctxKeyToken, tokenStr)
 		c.Next()
 	}
 }
 
-func validateRole(c *gin.Context, claims jwt.MapClaims, expectedIssuer string) bool {
-	role, ok := claims["role"]
-	if !ok {
-		logAuth(c, "missing role in token")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Missing role in token"})
-		c.Abort()
-		return false
+// authErrorResponse maps a validation failure to its public status, code and message.
+// Revocation and binding failures deliberately look like ordinary invalid or forbidden tokens.
+func authErrorResponse(err *auth.Error) (int, ErrorCode, string) {
+	switch {
+	case err.Reason == auth.ReasonMissing:
+		return http.StatusUnauthorized, CodeTokenMissing, "Token missing"
+	case err.Reason == auth.ReasonExpired || err.Reason == auth.ReasonTooOld:
+		return http.StatusUnauthorized, CodeTokenExpired, "Token has expired"
+	case err.Forbidden():
+		return http.StatusForbidden, CodeForbidden, "Access denied"
+	default:
+		return http.StatusUnauthorized, CodeTokenInvalid, "Invalid token"
+	}
+}
+
+func extractToken(c *gin.Context, allowPath, allowQuery bool) string {
+	if h := c.GetHeader("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(strings.TrimSpace(h), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
 	}
-	roleStr := strings.ToLower(fmt.Sprintf("%v", role))
-	allowed := []string{"admin", "user"}
-	if !contains(allowed, strings.TrimSpace(roleStr)) {
-		logAuth(c, fmt.Sprintf("insufficient permissions: role=%s", roleStr))
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
-		return false
+	if h := strings.TrimSpace(c.GetHeader(headerAPIKey)); h != "" {
+		return h
 	}
-	// Compare as string (JWT claim can be string or other type)
-	if fmt.Sprint(claims["iss"]) != expectedIssuer {
-		logAuth(c, fmt.Sprintf("invalid issuer: %v (expected %q)", claims["iss"], expectedIssuer))
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid token issuer", "expected": expectedIssuer})
-		c.Abort()
-		return false
+	if allowPath {
+		if t := c.Param(paramToken); t != "" {
+			return t
+		}
 	}
-	return true
+	if allowQuery && isBeacon(c) {
+		return c.Query(queryToken)
+	}
+	return ""
 }
 
-func validateExpClaim(c *gin.Context, claims jwt.MapClaims) bool {
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return true
-	}
-	if time.Unix(int64(exp), 0).Before(time.Now()) {
-		logAuth(c, "token expired")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
-		c.Abort()
-		return false
+// TenantRegistry reports whether a tenant is registered.
+type TenantRegistry interface {
+	Has(tenant string) bool
+}
+
+// KnownTenant returns a Gin middleware that rejects tenants missing from the registry with 403.
+func KnownTenant(reg TenantRegistry, rec MetricsRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := c.Param("tenant")
+		if !reg.Has(tenant) {
+			logAuth(c, "unknown tenant", "tenant", tenant, "reason", "unknown_tenant")
+			if rec != nil {
+				rec.AuthFailure("unknown_tenant")
+			}
+			abortWithError(c, http.StatusForbidden, CodeForbidden, "Access denied")
+			return
+		}
+		c.Next()
 	}
-	return true
 }
 
-func logAuth(c *gin.Context, msg string) {
-	slog.Warn("auth",
+func logAuth(c *gin.Context, msg string, attrs ...any) {
+	args := []any{
 		"msg", msg,
+		"request_id", requestID(c),
 		"origin", c.Request.Header.Get("Origin"),
 		"ip", c.ClientIP(),
 		"user_agent", c.Request.UserAgent(),
-	)
-}
-
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if strings.TrimSpace(s) == item {
-			return true
-		}
 	}
-	return false
+	slog.Warn("auth", append(args, attrs...)...)
 }
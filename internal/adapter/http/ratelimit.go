@@ -0,0 +1,66 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"collector-fe-instrumentation/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPRateLimit returns a Gin middleware that applies the per-IP request limit.
+// It runs before JWTAuth so floods of invalid tokens are rejected without validating them.
+func IPRateLimit(l *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d := l.AllowRequest(ratelimit.Keys{IP: c.ClientIP()}); !d.Allowed {
+			abortRateLimited(c, d)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimit returns a Gin middleware that applies the per-tenant and per-token request limits.
+// It runs after JWTAuth so the accepted token can be used as a key.
+func RateLimit(l *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := rateLimitKeys(c)
+		keys.IP = ""
+		if d := l.AllowRequest(keys); !d.Allowed {
+			abortRateLimited(c, d)
+			return
+		}
+		c.Next()
+	}
+}
+
+func rateLimitKeys(c *gin.Context) ratelimit.Keys {
+	return ratelimit.Keys{
+		Tenant: sanitizeParam(c.Param("tenant")),
+		Token:  c.GetString(ctxKeyToken),
+		IP:     c.ClientIP(),
+	}
+}
+
+func abortRateLimited(c *gin.Context, d ratelimit.Decision) {
+	secs := int(math.Ceil(d.RetryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	logAuth(c, "rate limited", "scope", d.Scope, "quota", d.Quota, "tenant", c.Param("tenant"))
+	if d.Quota {
+		abortWithError(c, http.StatusTooManyRequests, CodeQuotaExceeded, "Daily quota exceeded")
+		return
+	}
+	abortWithError(c, http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+}
+
+func dropReason(d ratelimit.Decision) string {
+	if d.Quota {
+		return string(CodeQuotaExceeded)
+	}
+	return string(CodeRateLimited)
+}
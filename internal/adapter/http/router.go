@@ -2,10 +2,13 @@ package http
 
 import (
 	"log/slog"
+	"net/http"
 	"strings"
 	"time"
 
 	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/ratelimit"
+	"collector-fe-instrumentation/internal/tracing"
 	"collector-fe-instrumentation/internal/usecase"
 
 	"github.com/gin-contrib/cors"
@@ -22,16 +25,71 @@ func Router(cfg *config.Config, collector *usecase.CollectorService, opts ...Rou
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	// Only the configured proxies may set the client IP (X-Forwarded-For) that the per-IP limit keys on.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		slog.Error("invalid trusted proxies, trusting none", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 	r.Use(gin.Recovery())
+	r.Use(RequestID())
+	if o.metrics != nil {
+		r.Use(Instrument(o.metrics))
+	}
 	r.Use(corsMiddleware(cfg))
 	r.Use(requestHeadersCORS())
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// /health predates /livez and stays as its alias.
+	r.GET("/health", livez)
+	readiness := o.readiness
+	if readiness == nil {
+		readiness = NewReadiness()
+	}
+	r.GET("/livez", livez)
+	r.GET("/readyz", readiness.readyz(cfg.AdminToken))
+
+	var authOpts []AuthOption
+	if o.revocations != nil {
+		authOpts = append(authOpts, WithRevocationChecker(o.revocations))
+	}
+	if o.metrics != nil {
+		authOpts = append(authOpts, WithAuthMetrics(o.metrics))
+	}
 
 	collectorHandler := NewCollectorHandler(collector, o.slog)
-	r.POST("/collect/:tenant/:token", JWTAuth(cfg), collectorHandler.Collect)
+	collectorHandler.metrics = o.metrics
+	var collectChain []gin.HandlerFunc
+	if o.tracer != nil {
+		collectChain = append(collectChain, Trace(o.tracer))
+	}
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimits.Enabled() {
+		limiter = ratelimit.New(cfg.RateLimits)
+		collectorHandler.limiter = limiter
+		if cfg.RateLimits.IP.Enabled() {
+			collectChain = append(collectChain, IPRateLimit(limiter))
+		}
+	}
+	collectChain = append(collectChain, JWTAuth(cfg, authOpts...))
+	if o.tenants != nil {
+		collectChain = append(collectChain, KnownTenant(o.tenants, o.metrics))
+	}
+	if limiter != nil {
+		collectChain = append(collectChain, RateLimit(limiter))
+	}
+	collectChain = append(collectChain, LimitBody(cfg.MaxBodyBytes, cfg.MaxDecompressedBytes), collectorHandler.Collect)
+	r.POST("/collect/:tenant", collectChain...)
+	beaconChain := append([]gin.HandlerFunc{BeaconMode()}, collectChain...)
+	r.POST("/beacon/:tenant", beaconChain...)
+	if cfg.AllowPathToken {
+		r.POST("/collect/:tenant/:token", collectChain...)
+		r.POST("/beacon/:tenant/:token", beaconChain...)
+	}
+
+	if cfg.AdminToken != "" && o.revocations != nil {
+		adminHandler := NewAdminHandler(o.revocations, o.slog)
+		admin := r.Group("/admin", AdminAuth(cfg.AdminToken))
+		admin.POST("/revocations/reload", adminHandler.ReloadRevocations)
+	}
 
 	return r
 }
@@ -49,32 +107,61 @@ func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 	corsCfg := cors.Config{
 		AllowMethods:     []string{"POST", "PUT", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Scope-OrgID", "X-Faro-Session-Id", "Origin", "Accept", "Referer", "User-Agent"},
-		ExposeHeaders:    []string{"Content-Length", "X-Kong-Request-ID", "X-Kong-Upstream-Latency"},
+		AllowHeaders:     []string{"Content-Type", "Content-Encoding", "Authorization", "X-Scope-OrgID", "X-Faro-Session-Id", headerAPIKey, headerRequestID, tracing.TraceparentHeader, tracing.TracestateHeader, "Origin", "Accept", "Referer", "User-Agent"},
+		ExposeHeaders:    []string{"Content-Length", headerRequestID, "Retry-After", "X-Kong-Request-ID", "X-Kong-Upstream-Latency"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 
-	if allowAll {
-		corsCfg.AllowAllOrigins = true
-	} else {
-		allowFunc := func(origin string) bool {
-			for _, allowed := range origins {
-				if origin == allowed {
+	allowFunc := func(origin string) bool {
+		for _, allowed := range origins {
+			if origin == allowed {
+				return true
+			}
+			if strings.HasPrefix(allowed, "https://*.") {
+				base := strings.TrimPrefix(allowed, "https://*.")
+				if strings.HasSuffix(origin, base) {
 					return true
 				}
-				if strings.HasPrefix(allowed, "https://*.") {
-					base := strings.TrimPrefix(allowed, "https://*.")
-					if strings.HasSuffix(origin, base) {
-						return true
-					}
-				}
 			}
-			return false
 		}
+		return false
+	}
+	if allowAll {
+		corsCfg.AllowAllOrigins = true
+		allowFunc = func(string) bool { return true }
+	} else {
 		corsCfg.AllowOriginFunc = allowFunc
 	}
-	return cors.New(corsCfg)
+	handler := cors.New(corsCfg)
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodOptions && strings.HasPrefix(c.Request.URL.Path, "/beacon/") {
+			beaconCORS(c, allowFunc)
+			return
+		}
+		handler(c)
+	}
+}
+
+// beaconCORS handles sendBeacon requests, which are simple CORS requests sent with credentials and
+// without a preflight: the origin is only checked here, so a disallowed one is dropped with the
+// usual beacon 204 instead of reaching the handler.
+func beaconCORS(c *gin.Context, allowed func(string) bool) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		c.Next()
+		return
+	}
+	if !allowed(origin) {
+		logAuth(c, "beacon origin not allowed", "reason", "origin")
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	// Credentialed requests need the exact origin rather than "*".
+	c.Header("Access-Control-Allow-Origin", origin)
+	c.Header("Access-Control-Allow-Credentials", "true")
+	c.Header("Vary", "Origin")
+	c.Next()
 }
 
 func requestHeadersCORS() gin.HandlerFunc {
@@ -90,7 +177,12 @@ func requestHeadersCORS() gin.HandlerFunc {
 type RouterOption func(*routerOptions)
 
 type routerOptions struct {
-	slog *slog.Logger
+	slog        *slog.Logger
+	revocations RevocationList
+	metrics     MetricsRecorder
+	tenants     TenantRegistry
+	readiness   *Readiness
+	tracer      *tracing.Tracer
 }
 
 // WithLogger sets the logger for the collect handler.
@@ -99,3 +191,38 @@ func WithLogger(l *slog.Logger) RouterOption {
 		o.slog = l
 	}
 }
+
+// WithRevocationList enables token revocation checks and, when ADMIN_TOKEN is set, the reload endpoint.
+func WithRevocationList(l RevocationList) RouterOption {
+	return func(o *routerOptions) {
+		o.revocations = l
+	}
+}
+
+// WithMetrics records request, auth and drop metrics to rec.
+func WithMetrics(rec MetricsRecorder) RouterOption {
+	return func(o *routerOptions) {
+		o.metrics = rec
+	}
+}
+
+// WithTenantRegistry rejects collect requests for tenants missing from reg.
+func WithTenantRegistry(reg TenantRegistry) RouterOption {
+	return func(o *routerOptions) {
+		o.tenants = reg
+	}
+}
+
+// WithReadiness serves GET /readyz from r, so shutdown can fail it before draining.
+func WithReadiness(r *Readiness) RouterOption {
+	return func(o *routerOptions) {
+		o.readiness = r
+	}
+}
+
+// WithTracer traces collect requests with t.
+func WithTracer(t *tracing.Tracer) RouterOption {
+	return func(o *routerOptions) {
+		o.tracer = t
+	}
+}
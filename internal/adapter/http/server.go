@@ -0,0 +1,216 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"collector-fe-instrumentation/internal/certfile"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+// ServerOptions configures the collector listener. Without CertFile or ACMEDomains it serves plain HTTP.
+type ServerOptions struct {
+	Addr string
+	// CertFile and KeyFile enable TLS; they are re-read when either file changes.
+	CertFile string
+	KeyFile  string
+	// MinTLSVersion is 1.0, 1.1, 1.2 (default) or 1.3.
+	MinTLSVersion string
+	// ACMEDomains enables automatic certificates for these hosts instead of CertFile/KeyFile.
+	ACMEDomains      []string
+	ACMEEmail        string
+	ACMEDirectoryURL string
+	// ACMECAFile is trusted when talking to the ACME directory (e.g. a local test CA).
+	ACMECAFile   string
+	ACMECacheDir string
+	// RedirectAddr, when set, serves HTTP there, redirecting to HTTPS and answering ACME http-01 challenges.
+	RedirectAddr string
+	Log          *slog.Logger
+}
+
+// Server runs the collector handler over HTTP, or over TLS with HTTP/2, plus the optional redirect listener.
+type Server struct {
+	opts     ServerOptions
+	srv      *http.Server
+	redirect *http.Server
+}
+
+// NewServer prepares the listeners; certificates and the ACME client are set up here so errors surface at startup.
+func NewServer(h http.Handler, opts ServerOptions) (*Server, error) {
+	log := opts.Log
+	if log == nil {
+		log = slog.Default()
+	}
+	errorLog := slog.NewLogLogger(log.Handler(), slog.LevelWarn)
+	s := &Server{
+		opts: opts,
+		srv:  &http.Server{Addr: opts.Addr, Handler: h, ReadHeaderTimeout: readHeaderTimeout, ErrorLog: errorLog},
+	}
+	if opts.CertFile == "" && len(opts.ACMEDomains) == 0 {
+		if opts.RedirectAddr != "" {
+			return nil, errors.New("server: redirect listener needs TLS")
+		}
+		return s, nil
+	}
+
+	minVersion, err := certfile.Version(opts.MinTLSVersion)
+	if err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+	redirect := http.Handler(http.HandlerFunc(s.redirectToHTTPS))
+	var tlsCfg *tls.Config
+	if len(opts.ACMEDomains) > 0 {
+		m, err := acmeManager(opts)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg = m.TLSConfig()
+		redirect = m.HTTPHandler(redirect)
+	} else {
+		certs, err := certfile.New(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("server: %w", err)
+		}
+		tlsCfg = &tls.Config{GetCertificate: certs.GetCertificate, NextProtos: []string{"h2", "http/1.1"}}
+	}
+	tlsCfg.MinVersion = minVersion
+	s.srv.TLSConfig = tlsCfg
+	if opts.RedirectAddr != "" {
+		s.redirect = &http.Server{Addr: opts.RedirectAddr, Handler: redirect, ReadHeaderTimeout: readHeaderTimeout, ErrorLog: errorLog}
+	}
+	return s, nil
+}
+
+func acmeManager(opts ServerOptions) (*autocert.Manager, error) {
+	client := &acme.Client{DirectoryURL: opts.ACMEDirectoryURL}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+	if opts.ACMECAFile != "" {
+		pem, err := os.ReadFile(opts.ACMECAFile) // #nosec G304 -- path comes from operator config
+		if err != nil {
+			return nil, fmt.Errorf("server: read acme ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("server: no certificates in acme ca file %s", opts.ACMECAFile)
+		}
+		client.HTTPClient = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		}
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(opts.ACMEDomains...),
+		Email:      opts.ACMEEmail,
+		Client:     client,
+	}
+	if opts.ACMECacheDir != "" {
+		m.Cache = autocert.DirCache(opts.ACMECacheDir)
+	}
+	return m, nil
+}
+
+// TLS reports whether the main listener terminates TLS.
+func (s *Server) TLS() bool {
+	return s.srv.TLSConfig != nil
+}
+
+// ListenAndServe listens on Addr (and RedirectAddr) and serves until Shutdown.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	var redirectLn net.Listener
+	if s.redirect != nil {
+		if redirectLn, err = net.Listen("tcp", s.opts.RedirectAddr); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+	return s.Serve(ln, redirectLn)
+}
+
+// Serve serves on ln and, when the redirect listener is enabled, on redirectLn.
+// It returns nil after Shutdown, or the first listener error.
+func (s *Server) Serve(ln, redirectLn net.Listener) error {
+	errc := make(chan error, 2)
+	if s.redirect != nil && redirectLn != nil {
+		go func() { errc <- ignoreClosed(s.redirect.Serve(redirectLn)) }()
+	}
+	go func() {
+		if s.TLS() {
+			// ServeTLS with empty file names uses TLSConfig and enables HTTP/2.
+			errc <- ignoreClosed(s.srv.ServeTLS(ln, "", ""))
+			return
+		}
+		errc <- ignoreClosed(s.srv.Serve(ln))
+	}()
+	err := <-errc
+	if err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = s.Shutdown(ctx)
+		cancel()
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	if s.redirect != nil {
+		errs = append(errs, s.redirect.Shutdown(ctx))
+	}
+	errs = append(errs, s.srv.Shutdown(ctx))
+	return errors.Join(errs...)
+}
+
+// Drain fails readiness, waits delay so load balancers stop sending traffic, then shuts down,
+// letting in-flight requests finish until ctx is done.
+func (s *Server) Drain(ctx context.Context, readiness *Readiness, delay time.Duration) error {
+	if readiness != nil {
+		readiness.SetDraining()
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+	return s.Shutdown(ctx)
+}
+
+// redirectToHTTPS sends clients to the same host and path on the TLS port; 308 keeps POST bodies.
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(s.opts.Addr); err == nil && port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
+
+func ignoreClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"collector-fe-instrumentation/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trace returns a Gin middleware that starts the request's server span, continuing an incoming traceparent
+// and tracestate. The tracer's sample ratio decides whether it is recorded, whatever the client asks for.
+// It runs first on the collect routes so the auth, decode and sink spans nest under it.
+func Trace(tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		remote, _ := tracing.Extract(c.Request.Header)
+		ctx, span := tracer.Start(c.Request.Context(), c.Request.Method+" "+c.FullPath(), tracing.KindServer, remote,
+			tracing.String("http.request.method", c.Request.Method),
+			tracing.String("http.route", c.FullPath()),
+			tracing.String("tenant", sanitizeParam(c.Param("tenant"))),
+			tracing.String("request_id", requestID(c)),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("HTTP %d", status))
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package kafka publishes Faro items to Kafka topics.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	defaultTopic              = "faro-{kind}"
+	defaultMaxBufferedRecords = 10000
+	defaultDeliveryTimeout    = 30 * time.Second
+	// maxTopicLength is Kafka's limit on topic names.
+	maxTopicLength = 249
+	// otherApp replaces {app} values missing from Options.Apps.
+	otherApp = "other"
+)
+
+// Options configures the Producer.
+type Options struct {
+	Brokers []string
+	// Topic may use {tenant}, {kind} and {app}; default "faro-{kind}". {app} comes from the browser,
+	// so it needs Apps.
+	Topic string
+	// Apps lists the app names {app} may expand to; any other app publishes to "other".
+	Apps []string
+	// AutoCreateTopics lets the brokers create missing topics on the first write.
+	AutoCreateTopics bool
+	// Batch publishes one record per topic and session holding a JSON array, instead of one per item.
+	Batch bool
+	// Compression is none, gzip, snappy, lz4 or zstd.
+	Compression string
+	// Acks is all (default), leader or none.
+	Acks string
+	// MaxBufferedRecords bounds records waiting for delivery; Write blocks while it is full.
+	MaxBufferedRecords int
+	DeliveryTimeout    time.Duration
+}
+
+// Producer is a sink publishing items keyed by session ID, so a session's items stay ordered
+// within one partition.
+type Producer struct {
+	client *kgo.Client
+	opts   Options
+	apps   map[string]bool
+	now    func() time.Time
+}
+
+// message is the record value for one item.
+type message struct {
+	Time      string                 `json:"time"`
+	Tenant    string                 `json:"tenant"`
+	Kind      string                 `json:"kind"`
+	Timestamp string                 `json:"timestamp,omitempty"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// NewProducer creates the Kafka client; brokers are contacted lazily on the first write.
+func NewProducer(opts Options) (*Producer, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers")
+	}
+	if opts.Topic == "" {
+		opts.Topic = defaultTopic
+	}
+	if opts.MaxBufferedRecords <= 0 {
+		opts.MaxBufferedRecords = defaultMaxBufferedRecords
+	}
+	if opts.DeliveryTimeout <= 0 {
+		opts.DeliveryTimeout = defaultDeliveryTimeout
+	}
+	if strings.Contains(opts.Topic, "{app}") && len(opts.Apps) == 0 {
+		return nil, errors.New("kafka: {app} in the topic needs an app allowlist")
+	}
+	apps := make(map[string]bool, len(opts.Apps))
+	for _, a := range opts.Apps {
+		apps[a] = true
+	}
+	codec, err := compressionCodec(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	kopts := []kgo.Opt{
+		kgo.SeedBrokers(opts.Brokers...),
+		kgo.ProducerBatchCompression(codec),
+		kgo.MaxBufferedRecords(opts.MaxBufferedRecords),
+		kgo.RecordDeliveryTimeout(opts.DeliveryTimeout),
+	}
+	if opts.AutoCreateTopics {
+		kopts = append(kopts, kgo.AllowAutoTopicCreation())
+	}
+	switch opts.Acks {
+	case "", "all":
+		kopts = append(kopts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case "leader":
+		kopts = append(kopts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	case "none":
+		kopts = append(kopts, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	default:
+		return nil, fmt.Errorf("kafka: unknown acks %q", opts.Acks)
+	}
+	client, err := kgo.NewClient(kopts...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	return &Producer{client: client, opts: opts, apps: apps, now: time.Now}, nil
+}
+
+func compressionCodec(name string) (kgo.CompressionCodec, error) {
+	switch name {
+	case "", "none":
+		return kgo.NoCompression(), nil
+	case "gzip":
+		return kgo.GzipCompression(), nil
+	case "snappy":
+		return kgo.SnappyCompression(), nil
+	case "lz4":
+		return kgo.Lz4Compression(), nil
+	case "zstd":
+		return kgo.ZstdCompression(), nil
+	}
+	return kgo.CompressionCodec{}, fmt.Errorf("kafka: unknown compression %q", name)
+}
+
+// Write implements usecase.Sink. It waits until every record is acknowledged (per Acks),
+// blocking while the producer buffer is full so the sink queue absorbs the backpressure.
+func (p *Producer) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	records, err := p.records(tenantID, items)
+	if err != nil {
+		return err
+	}
+	if err := p.client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("kafka produce: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered records and closes the client.
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.client.Flush(ctx)
+	p.client.Close()
+	return err
+}
+
+func (p *Producer) records(tenantID string, items []domain.Item) ([]*kgo.Record, error) {
+	now := p.now().UTC().Format(time.RFC3339Nano)
+	if !p.opts.Batch {
+		records := make([]*kgo.Record, 0, len(items))
+		for _, it := range items {
+			topic, err := p.topic(tenantID, it)
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(toMessage(tenantID, it, now))
+			if err != nil {
+				return nil, fmt.Errorf("marshal item: %w", err)
+			}
+			records = append(records, &kgo.Record{
+				Topic: topic,
+				Key:   recordKey(sessionID(it)),
+				Value: value,
+				Headers: []kgo.RecordHeader{
+					{Key: "tenant", Value: []byte(tenantID)},
+					{Key: "kind", Value: []byte(it.Kind)},
+				},
+			})
+		}
+		return records, nil
+	}
+
+	type group struct {
+		topic, key string
+		messages   []message
+	}
+	var groups []*group
+	index := make(map[[2]string]*group)
+	for _, it := range items {
+		topic, err := p.topic(tenantID, it)
+		if err != nil {
+			return nil, err
+		}
+		k := [2]string{topic, sessionID(it)}
+		g, ok := index[k]
+		if !ok {
+			g = &group{topic: k[0], key: k[1]}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.messages = append(g.messages, toMessage(tenantID, it, now))
+	}
+	records := make([]*kgo.Record, 0, len(groups))
+	for _, g := range groups {
+		value, err := json.Marshal(g.messages)
+		if err != nil {
+			return nil, fmt.Errorf("marshal batch: %w", err)
+		}
+		records = append(records, &kgo.Record{
+			Topic:   g.topic,
+			Key:     recordKey(g.key),
+			Value:   value,
+			Headers: []kgo.RecordHeader{{Key: "tenant", Value: []byte(tenantID)}},
+		})
+	}
+	return records, nil
+}
+
+// topic expands the topic pattern for an item. Apps outside the allowlist map to "other", and
+// names Kafka would reject are an error rather than a record that can never be delivered.
+func (p *Producer) topic(tenantID string, it domain.Item) (string, error) {
+	app := otherApp
+	if it.Meta != nil && p.apps[it.Meta.App.Name] {
+		app = it.Meta.App.Name
+	}
+	topic := strings.NewReplacer(
+		"{tenant}", topicPart(tenantID),
+		"{kind}", it.Kind,
+		"{app}", topicPart(app),
+	).Replace(p.opts.Topic)
+	if len(topic) > maxTopicLength || topic == "." || topic == ".." {
+		return "", fmt.Errorf("kafka: invalid topic name %q", topic)
+	}
+	return topic, nil
+}
+
+func sessionID(it domain.Item) string {
+	if it.Meta == nil {
+		return ""
+	}
+	return it.Meta.Session.ID
+}
+
+// recordKey leaves items without a session unkeyed so they spread over partitions.
+func recordKey(session string) []byte {
+	if session == "" {
+		return nil
+	}
+	return []byte(session)
+}
+
+func toMessage(tenantID string, it domain.Item, now string) message {
+	return message{
+		Time:      now,
+		Tenant:    tenantID,
+		Kind:      it.Kind,
+		Timestamp: it.Timestamp,
+		Level:     it.Level(),
+		Message:   it.Message(),
+		Fields:    it.AllFields(),
+	}
+}
+
+// topicPart replaces characters Kafka does not allow in topic names.
+func topicPart(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, r := range v {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
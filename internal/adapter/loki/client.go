@@ -10,39 +10,85 @@ import (
 	"time"
 
 	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
 )
 
 const (
 	defaultTimeout = 15 * time.Second
 	lokiPushPath   = "/loki/api/v1/push"
+	lokiReadyPath  = "/ready"
 )
 
+// Auth types accepted in Options.Auth.
+const (
+	AuthNone   = "none"
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+)
+
+// Auth holds the credentials sent to Loki.
+type Auth struct {
+	// Type is none, bearer or basic; empty means bearer when Token is set, none otherwise.
+	Type     string
+	Token    string
+	Username string
+	Password string
+}
+
+// Options configures a Client.
+type Options struct {
+	URL  string
+	Auth Auth
+	// OrgID overrides the X-Scope-OrgID header; empty sends the collector tenant.
+	OrgID   string
+	Headers map[string]string
+	Timeout time.Duration
+	// Transport carries TLS, proxy and pool settings (see NewTransport); nil uses http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
 // Client sends log streams to Grafana Loki.
 type Client struct {
 	baseURL    string
-	token      string
+	opts       Options
 	httpClient *http.Client
 }
 
 // NewClient creates a Loki client. baseURL is the Loki base URL (e.g. https://loki.elvenobservability.com).
 func NewClient(baseURL, token string, timeout time.Duration) *Client {
-	if timeout == 0 {
-		timeout = defaultTimeout
+	return New(Options{URL: baseURL, Auth: Auth{Token: token}, Timeout: timeout})
+}
+
+// New creates a Loki client from opts.
+func New(opts Options) *Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	url := opts.URL
+	if len(url) > 0 && url[len(url)-1] == '/' {
+		url = url[:len(url)-1]
 	}
 	return &Client{
-		baseURL: baseURL,
-		token:   token,
+		baseURL: url,
+		opts:    opts,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   opts.Timeout,
+			Transport: opts.Transport,
 		},
 	}
 }
 
-// Push implements usecase.LokiWriter.
-func (c *Client) Push(ctx context.Context, tenantID string, streams []domain.LokiStream) error {
+// Push implements usecase.LokiWriter. When ctx is traced the request carries a traceparent for the push span.
+func (c *Client) Push(ctx context.Context, tenantID string, streams []domain.LokiStream) (err error) {
 	if len(streams) == 0 {
 		return nil
 	}
+	ctx, span := tracing.Start(ctx, "loki.push", tracing.KindClient,
+		tracing.String("server.address", hostOf(c.baseURL)), tracing.Int("streams", len(streams)))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	payload := struct {
 		Streams []domain.LokiStream `json:"streams"`
 	}{Streams: streams}
@@ -51,19 +97,21 @@ func (c *Client) Push(ctx context.Context, tenantID string, streams []domain.Lok
 		return fmt.Errorf("marshal payload: %w", err)
 	}
 
-	url := c.baseURL
-	if len(url) > 0 && url[len(url)-1] == '/' {
-		url = url[:len(url)-1]
-	}
-	url += lokiPushPath
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+lokiPushPath, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
+	for k, v := range c.opts.Headers {
+		req.Header.Set(k, v)
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Scope-OrgID", tenantID)
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	orgID := tenantID
+	if c.opts.OrgID != "" {
+		orgID = c.opts.OrgID
+	}
+	req.Header.Set("X-Scope-OrgID", orgID)
+	setAuth(req, c.opts.Auth)
+	tracing.Inject(ctx, req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -73,7 +121,54 @@ func (c *Client) Push(ctx context.Context, tenantID string, streams []domain.Lok
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode/100 == 4 {
+			// Bad streams and per-tenant limits (400, 429) are not Loki being down.
+			return fmt.Errorf("%w: loki returned %d: %s", domain.ErrSinkRejected, resp.StatusCode, string(b))
+		}
 		return fmt.Errorf("loki returned %d: %s", resp.StatusCode, string(b))
 	}
 	return nil
 }
+
+// Ready calls Loki's GET /ready and fails unless it answers 200.
+func (c *Client) Ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+lokiReadyPath, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range c.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	setAuth(req, c.opts.Auth)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return &notReadyError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// notReadyError is a /ready answer other than 200, as opposed to a network failure.
+type notReadyError struct {
+	status int
+}
+
+func (e *notReadyError) Error() string {
+	return fmt.Sprintf("loki /ready returned %d", e.status)
+}
+
+func setAuth(req *http.Request, a Auth) {
+	switch a.Type {
+	case AuthNone:
+	case AuthBasic:
+		req.SetBasicAuth(a.Username, a.Password)
+	default:
+		if a.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+a.Token)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNotProbed is reported until the first probe round completes.
+var errNotProbed = errors.New("not probed yet")
+
+// Prober polls GET /ready on the Loki endpoints returned by targets and keeps the last results,
+// so readiness checks never wait on the network.
+type Prober struct {
+	targets  func() []Options
+	interval time.Duration
+	timeout  time.Duration
+	log      *slog.Logger
+
+	mu      sync.RWMutex
+	results map[string]error
+}
+
+// NewProber creates a prober; targets is called every round so registry reloads are picked up.
+func NewProber(targets func() []Options, interval time.Duration, log *slog.Logger) *Prober {
+	if log == nil {
+		log = slog.Default()
+	}
+	timeout := 5 * time.Second
+	if interval < timeout {
+		timeout = interval
+	}
+	return &Prober{targets: targets, interval: interval, timeout: timeout, log: log}
+}
+
+// Run probes immediately and then every interval until ctx is done.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe runs one round over the distinct endpoint URLs.
+func (p *Prober) Probe(ctx context.Context) {
+	byURL := make(map[string]Options)
+	for _, opts := range p.targets() {
+		if opts.URL != "" {
+			byURL[opts.URL] = opts
+		}
+	}
+	results := make(map[string]error, len(byURL))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for u, opts := range byURL {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, p.timeout)
+			err := New(opts).Ready(pctx)
+			cancel()
+			mu.Lock()
+			results[u] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	prev := p.results
+	p.results = results
+	p.mu.Unlock()
+	for u, err := range results {
+		prevErr, seen := prev[u]
+		if err != nil && (!seen || prevErr == nil) {
+			p.log.Warn("loki probe failed", "url", u, "error", err)
+		} else if err == nil && seen && prevErr != nil {
+			p.log.Info("loki probe recovered", "url", u)
+		}
+	}
+}
+
+// Err is nil while at least one endpoint answered /ready; one unreachable tenant cluster does not
+// take the whole collector out of rotation.
+func (p *Prober) Err() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.results == nil {
+		return errNotProbed
+	}
+	if len(p.results) == 0 {
+		return nil
+	}
+	var failed []string
+	for u, err := range p.results {
+		if err == nil {
+			return nil
+		}
+		failed = append(failed, hostOf(u)+": "+reason(err))
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("no loki endpoint ready (%s)", strings.Join(failed, "; "))
+}
+
+// hostOf keeps only the host so readiness output does not echo paths or credentials.
+func hostOf(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "invalid url"
+}
+
+func reason(err error) string {
+	var nr *notReadyError
+	if errors.As(err, &nr) {
+		return nr.Error()
+	}
+	return "unreachable"
+}
@@ -0,0 +1,113 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/tenant"
+	"collector-fe-instrumentation/internal/domain"
+)
+
+// Resolver returns the Loki endpoint of a tenant, or false when the tenant is not known.
+type Resolver func(tenantID string) (Options, bool)
+
+// RegistryResolver resolves a tenant's endpoint from the registry. Unset fields come from defaults
+// (the LOKI_* settings), except that the default credentials and defaults.Transport (with the
+// LOKI_CA_FILE, client certificate and server name) only go with the default URL, so they are never
+// sent to a tenant's own endpoint. Tenant URLs share one transport with the same proxy and pool
+// settings but the system roots and no client certificate.
+func RegistryResolver(reg *tenant.Registry, defaults Options) Resolver {
+	tenantTransport := plainTransport(defaults.Transport)
+	return func(tenantID string) (Options, bool) {
+		t, ok := reg.Lookup(tenantID)
+		if !ok {
+			return Options{}, false
+		}
+		opts := Options{
+			URL:       t.Loki.URL,
+			OrgID:     t.Loki.OrgID,
+			Headers:   t.Loki.Headers,
+			Timeout:   time.Duration(t.Loki.Timeout),
+			Transport: tenantTransport,
+		}
+		if opts.URL == "" {
+			opts.URL, opts.Auth = defaults.URL, defaults.Auth
+		}
+		if opts.URL == defaults.URL {
+			opts.Transport = defaults.Transport
+		}
+		if opts.Timeout == 0 {
+			opts.Timeout = defaults.Timeout
+		}
+		if a := t.Loki.Auth; a != nil {
+			opts.Auth = Auth{Type: a.Type, Token: a.Token, Username: a.Username, Password: a.Password}
+		}
+		return opts, true
+	}
+}
+
+// plainTransport copies rt without its custom TLS settings, keeping only the minimum TLS version.
+// It returns nil (http.DefaultTransport) when rt is not an *http.Transport.
+func plainTransport(rt http.RoundTripper) http.RoundTripper {
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	plain := t.Clone()
+	plain.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.TLSClientConfig != nil && t.TLSClientConfig.MinVersion != 0 {
+		plain.TLSClientConfig.MinVersion = t.TLSClientConfig.MinVersion
+	}
+	return plain
+}
+
+// TenantWriter pushes each tenant's streams to its own Loki endpoint.
+// Clients are created on first use and replaced when the tenant's options change (e.g. after a registry reload).
+type TenantWriter struct {
+	resolve Resolver
+
+	mu      sync.Mutex
+	clients map[string]tenantClient
+}
+
+type tenantClient struct {
+	opts   Options
+	client *Client
+}
+
+// NewTenantWriter creates a writer that looks up the endpoint of every push with resolve.
+func NewTenantWriter(resolve Resolver) *TenantWriter {
+	return &TenantWriter{resolve: resolve, clients: make(map[string]tenantClient)}
+}
+
+// Push implements usecase.LokiWriter. Unknown tenants fail with domain.ErrUnknownTenant.
+func (w *TenantWriter) Push(ctx context.Context, tenantID string, streams []domain.LokiStream) error {
+	client, err := w.client(tenantID)
+	if err != nil {
+		return err
+	}
+	return client.Push(ctx, tenantID, streams)
+}
+
+func (w *TenantWriter) client(tenantID string) (*Client, error) {
+	opts, ok := w.resolve(tenantID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnknownTenant, tenantID)
+	}
+	if opts.URL == "" {
+		return nil, fmt.Errorf("loki: no url for tenant %s", tenantID)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if c, ok := w.clients[tenantID]; ok && reflect.DeepEqual(c.opts, opts) {
+		return c.client, nil
+	}
+	c := New(opts)
+	w.clients[tenantID] = tenantClient{opts: opts, client: c}
+	return c, nil
+}
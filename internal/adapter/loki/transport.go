@@ -0,0 +1,111 @@
+package loki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"collector-fe-instrumentation/internal/certfile"
+)
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// TransportOptions configures TLS, proxy and connection pooling of the Loki HTTP transport.
+type TransportOptions struct {
+	// CAFile is a PEM bundle trusted instead of the system roots.
+	CAFile string
+	// CertFile and KeyFile are the client certificate for mTLS; they are re-read when either file changes.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified in the server certificate.
+	ServerName string
+	// MinVersion is 1.0, 1.1, 1.2 (default) or 1.3.
+	MinVersion string
+	// ProxyURL is used for every request; empty honours HTTP_PROXY/HTTPS_PROXY/NO_PROXY.
+	ProxyURL            string
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+}
+
+// NewTransport builds the HTTP transport for Loki clients. Share one transport across clients to share the pool.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	tlsCfg, err := tlsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if opts.ProxyURL != "" {
+		u, err := url.Parse(opts.ProxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("loki: invalid proxy url %q", opts.ProxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaultMaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = defaultKeepAlive
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: opts.KeepAlive}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsCfg,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}, nil
+}
+
+func tlsConfig(opts TransportOptions) (*tls.Config, error) {
+	minVersion, err := certfile.Version(opts.MinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("loki: %w", err)
+	}
+	cfg := &tls.Config{MinVersion: minVersion, ServerName: opts.ServerName} // #nosec G402 -- MinVersion defaults to 1.2
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile) // #nosec G304 -- path comes from operator config
+		if err != nil {
+			return nil, fmt.Errorf("loki: read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("loki: no certificates in ca file %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("loki: client cert and key must be set together")
+	}
+	if opts.CertFile != "" {
+		r, err := certfile.New(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loki: client %w", err)
+		}
+		cfg.GetClientCertificate = r.GetClientCertificate
+	}
+	return cfg, nil
+}
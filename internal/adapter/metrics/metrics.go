@@ -0,0 +1,209 @@
+// Package metrics exposes the collector's own Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "faro_collector"
+
+// otherTenant labels tenants beyond the bound set by WithTenants or WithMaxTenants.
+const otherTenant = "other"
+
+// DefaultMaxTenants bounds the tenant label when no registry is configured.
+const DefaultMaxTenants = 100
+
+// Option configures Metrics.
+type Option func(*Metrics)
+
+// WithTenants labels only the tenants known reports, e.g. those in the tenant registry;
+// every other tenant is counted as "other".
+func WithTenants(known func(tenant string) bool) Option {
+	return func(m *Metrics) {
+		m.knownTenant = known
+	}
+}
+
+// WithMaxTenants labels the first n distinct tenants and counts the rest as "other".
+// It applies when WithTenants is not set (default DefaultMaxTenants).
+func WithMaxTenants(n int) Option {
+	return func(m *Metrics) {
+		m.maxTenants = n
+	}
+}
+
+// Metrics holds the collector instruments and the registry they are exported from.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	httpRequestBytes *prometheus.HistogramVec
+	itemsReceived    *prometheus.CounterVec
+	itemsDropped     *prometheus.CounterVec
+	authFailures     *prometheus.CounterVec
+	lokiPushDuration *prometheus.HistogramVec
+	lokiPushErrors   *prometheus.CounterVec
+	queueDepth       *prometheus.GaugeVec
+
+	// The tenant label comes from the request path, so it is bounded: see WithTenants and WithMaxTenants.
+	knownTenant func(string) bool
+	maxTenants  int
+	mu          sync.Mutex
+	tenants     map[string]struct{}
+}
+
+// New creates the instruments on a fresh registry, together with Go runtime and process collectors.
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "http_requests_total",
+			Help: "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_duration_seconds",
+			Help:    "HTTP request latency by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route"}),
+		httpRequestBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_body_bytes",
+			Help:    "Decoded request body size by route.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 9), // 256B .. 16MiB
+		}, []string{"route"}),
+		itemsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "items_received_total",
+			Help: "Faro items accepted, by tenant and kind (log, event, measurement, exception).",
+		}, []string{"tenant", "kind"}),
+		itemsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "items_dropped_total",
+			Help: "Faro items dropped, by tenant and reason.",
+		}, []string{"tenant", "reason"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "auth_failures_total",
+			Help: "Rejected collector tokens by reason.",
+		}, []string{"reason"}),
+		lokiPushDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "loki_push_duration_seconds",
+			Help:    "Loki push latency by outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		lokiPushErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "loki_push_errors_total",
+			Help: "Failed Loki pushes by tenant.",
+		}, []string{"tenant"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "queue_depth",
+			Help: "Items waiting in each sink queue.",
+		}, []string{"sink"}),
+		maxTenants: DefaultMaxTenants,
+		tenants:    make(map[string]struct{}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.httpRequestBytes,
+		m.itemsReceived, m.itemsDropped, m.authFailures,
+		m.lokiPushDuration, m.lokiPushErrors, m.queueDepth,
+	)
+	for _, fn := range opts {
+		fn(m)
+	}
+	return m
+}
+
+// tenantLabel returns tenant when it is within the bound, otherwise "other".
+func (m *Metrics) tenantLabel(tenant string) string {
+	if m.knownTenant != nil {
+		if m.knownTenant(tenant) {
+			return tenant
+		}
+		return otherTenant
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tenants[tenant]; ok {
+		return tenant
+	}
+	if len(m.tenants) >= m.maxTenants {
+		return otherTenant
+	}
+	m.tenants[tenant] = struct{}{}
+	return tenant
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// ObserveRequest implements the HTTP adapter's MetricsRecorder.
+func (m *Metrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(route).Observe(d.Seconds())
+}
+
+// ObserveBodySize implements the HTTP adapter's MetricsRecorder.
+func (m *Metrics) ObserveBodySize(route string, bytes int64) {
+	m.httpRequestBytes.WithLabelValues(route).Observe(float64(bytes))
+}
+
+// AuthFailure implements the HTTP adapter's MetricsRecorder.
+func (m *Metrics) AuthFailure(reason string) {
+	m.authFailures.WithLabelValues(reason).Inc()
+}
+
+// ItemsDropped implements the HTTP adapter's MetricsRecorder and usecase.Recorder.
+func (m *Metrics) ItemsDropped(tenant, reason string, n int) {
+	if n > 0 {
+		m.itemsDropped.WithLabelValues(m.tenantLabel(tenant), reason).Add(float64(n))
+	}
+}
+
+// ItemsReceived implements usecase.Recorder.
+func (m *Metrics) ItemsReceived(tenant, kind string, n int) {
+	if n > 0 {
+		m.itemsReceived.WithLabelValues(m.tenantLabel(tenant), kind).Add(float64(n))
+	}
+}
+
+// SetQueueDepth records the number of items waiting in a sink queue.
+func (m *Metrics) SetQueueDepth(sink string, n int) {
+	m.queueDepth.WithLabelValues(sink).Set(float64(n))
+}
+
+// InstrumentLoki wraps w to record push latency and errors.
+func (m *Metrics) InstrumentLoki(w usecase.LokiWriter) usecase.LokiWriter {
+	return &instrumentedLoki{next: w, m: m}
+}
+
+type instrumentedLoki struct {
+	next usecase.LokiWriter
+	m    *Metrics
+}
+
+func (l *instrumentedLoki) Push(ctx context.Context, tenantID string, streams []domain.LokiStream) error {
+	start := time.Now()
+	err := l.next.Push(ctx, tenantID, streams)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		l.m.lokiPushErrors.WithLabelValues(l.m.tenantLabel(tenantID)).Inc()
+	}
+	l.m.lokiPushDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	return err
+}
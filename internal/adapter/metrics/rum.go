@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"collector-fe-instrumentation/internal/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OverflowLabel replaces label values once a label has reached its distinct-value cap for a tenant.
+const OverflowLabel = "other"
+
+// Faro event names counted as session starts and page views.
+var (
+	sessionStartEvents = map[string]bool{"session_start": true}
+	pageViewEvents     = map[string]bool{"view_changed": true, "page_view": true}
+)
+
+// timeVitals are Web Vitals reported in milliseconds; they are exported in seconds.
+var timeVitals = map[string]bool{"lcp": true, "fcp": true, "ttfb": true, "inp": true, "fid": true}
+
+// RUM turns Faro measurements into Prometheus histograms labeled by tenant, app,
+// environment, view and browser, and counts sessions, page views and errors.
+// It implements usecase.PayloadObserver.
+type RUM struct {
+	webVitals    *prometheus.HistogramVec
+	cls          *prometheus.HistogramVec
+	measurements *prometheus.HistogramVec
+	sessions     *prometheus.CounterVec
+	pageViews    *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	values       *labelValues
+	series       *seriesLimit
+}
+
+// NewRUM registers the RUM histograms on reg. maxLabelValues caps the distinct values kept per
+// label and tenant, except for tenant itself; later values are reported as OverflowLabel. maxSeries caps the label sets
+// across all RUM metrics; observations that would create more are dropped. Zero disables a cap.
+func NewRUM(reg prometheus.Registerer, maxLabelValues, maxSeries int) *RUM {
+	labels := []string{"tenant", "app", "environment", "view", "browser"}
+	r := &RUM{
+		webVitals: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "faro_rum", Name: "web_vital_seconds",
+			Help:    "Timing Web Vitals (LCP, FCP, TTFB, INP, FID) reported by browsers.",
+			Buckets: []float64{0.05, 0.1, 0.2, 0.5, 0.8, 1, 1.8, 2.5, 3, 4, 6, 10, 20},
+		}, append(labels, "name")),
+		cls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "faro_rum", Name: "cls",
+			Help:    "Cumulative Layout Shift reported by browsers.",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.15, 0.25, 0.5, 1},
+		}, labels),
+		measurements: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "faro_rum", Name: "measurement_value",
+			Help:    "Other Faro measurement values, by measurement type and value name.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+		}, append(labels, "type", "name")),
+		sessions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "faro_rum", Name: "sessions_total",
+			Help: "Sessions started (session_start events).",
+		}, []string{"tenant", "app", "environment"}),
+		pageViews: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "faro_rum", Name: "page_views_total",
+			Help: "Page views (view_changed and page_view events).",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "faro_rum", Name: "errors_total",
+			Help: "Exceptions reported by browsers.",
+		}, labels),
+		values: newLabelValues(maxLabelValues),
+		series: newSeriesLimit(maxSeries),
+	}
+	reg.MustRegister(r.webVitals, r.cls, r.measurements, r.sessions, r.pageViews, r.errors)
+	return r
+}
+
+// ObservePayload records every measurement value, session start, page view and exception of the payload.
+func (r *RUM) ObservePayload(tenant string, p *domain.Payload) {
+	if len(p.Measurements) == 0 && len(p.Events) == 0 && len(p.Exceptions) == 0 {
+		return
+	}
+	base := r.baseLabels(tenant, p)
+	for _, e := range p.Events {
+		switch {
+		case sessionStartEvents[e.Name]:
+			if r.series.allow("sessions", base[:3]) {
+				r.sessions.WithLabelValues(base[:3]...).Inc()
+			}
+		case pageViewEvents[e.Name]:
+			if r.series.allow("page_views", base) {
+				r.pageViews.WithLabelValues(base...).Inc()
+			}
+		}
+	}
+	if len(p.Exceptions) > 0 && r.series.allow("errors", base) {
+		r.errors.WithLabelValues(base...).Add(float64(len(p.Exceptions)))
+	}
+	for _, m := range p.Measurements {
+		for name, v := range m.Values {
+			name = strings.ToLower(name)
+			switch {
+			case timeVitals[name]:
+				if lvs := append(base[:len(base):len(base)], name); r.series.allow("web_vital", lvs) {
+					r.webVitals.WithLabelValues(lvs...).Observe(v / 1000)
+				}
+			case name == "cls":
+				if r.series.allow("cls", base) {
+					r.cls.WithLabelValues(base...).Observe(v)
+				}
+			default:
+				lvs := append(base[:len(base):len(base)],
+					r.values.bound(base[0], "type", m.Type),
+					r.values.bound(base[0], "name", name))
+				if r.series.allow("measurement", lvs) {
+					r.measurements.WithLabelValues(lvs...).Observe(v)
+				}
+			}
+		}
+	}
+}
+
+// baseLabels bounds every label but tenant: remote-write routes series to their tenant by it
+// (X-Scope-OrgID), so it is never folded into OverflowLabel. The tenants reaching the collector are
+// bounded by authentication and the tenant registry, and all series by maxSeries.
+func (r *RUM) baseLabels(tenant string, p *domain.Payload) []string {
+	return []string{
+		tenant,
+		r.values.bound(tenant, "app", p.Meta.App.Name),
+		r.values.bound(tenant, "environment", p.Meta.App.Environment),
+		r.values.bound(tenant, "view", p.Meta.View.Name),
+		r.values.bound(tenant, "browser", p.Meta.Browser.Name),
+	}
+}
+
+// labelValues caps the number of distinct values seen per tenant and label, so one tenant
+// cannot use up another's values.
+type labelValues struct {
+	max  int
+	mu   sync.Mutex
+	seen map[[2]string]map[string]struct{}
+}
+
+func newLabelValues(max int) *labelValues {
+	return &labelValues{max: max, seen: make(map[[2]string]map[string]struct{})}
+}
+
+func (l *labelValues) bound(tenant, label, value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	if l.max <= 0 {
+		return value
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := [2]string{tenant, label}
+	set, ok := l.seen[key]
+	if !ok {
+		set = make(map[string]struct{})
+		l.seen[key] = set
+	}
+	if _, ok := set[value]; ok {
+		return value
+	}
+	if len(set) >= l.max {
+		return OverflowLabel
+	}
+	set[value] = struct{}{}
+	return value
+}
+
+// seriesLimit caps the label sets recorded across all RUM metrics.
+type seriesLimit struct {
+	max  int
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newSeriesLimit(max int) *seriesLimit {
+	return &seriesLimit{max: max, seen: make(map[string]struct{})}
+}
+
+// allow reports whether the series exists or there is room for it, and records it.
+func (l *seriesLimit) allow(metric string, lvs []string) bool {
+	if l.max <= 0 {
+		return true
+	}
+	key := metric + "\xff" + strings.Join(lvs, "\xff")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[key]; ok {
+		return true
+	}
+	if len(l.seen) >= l.max {
+		return false
+	}
+	l.seen[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,224 @@
+package ndjson
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeFormat = "20060102T150405.000"
+
+// RotateOptions controls when RotatingFile starts a new file and what happens to old ones.
+// Zero values disable the corresponding behavior.
+type RotateOptions struct {
+	// MaxBytes rotates before a write would make the file larger than this.
+	MaxBytes int64
+	// Interval rotates files older than this.
+	Interval time.Duration
+	// MaxFiles keeps at most this many rotated files, deleting the oldest.
+	MaxFiles int
+	// Compress gzips rotated files in the background.
+	Compress bool
+}
+
+// RotatingFile is an io.Writer appending to path and rotating it to path.<time>[.gz].
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+	log  *slog.Logger
+	now  func() time.Time
+
+	mu sync.Mutex
+	// f is nil after a failed rotation until the next Write reopens path, and after Close.
+	f        *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	wg       sync.WaitGroup
+}
+
+// OpenRotating opens (or appends to) path, creating its directory when needed.
+func OpenRotating(path string, opts RotateOptions, log *slog.Logger) (*RotatingFile, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	r := &RotatingFile{path: path, opts: opts, log: log, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends p, rotating first when the size or age limit is reached. After a failed
+// rotation it retries opening the file, so a transient error only fails the writes it hits.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new file now.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.f == nil {
+		return r.open()
+	}
+	return r.rotate()
+}
+
+// Close closes the current file and waits for pending compressions.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	r.closed = true
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+func (r *RotatingFile) shouldRotate(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxBytes > 0 && r.size+n > r.opts.MaxBytes {
+		return true
+	}
+	return r.opts.Interval > 0 && r.now().Sub(r.openedAt) >= r.opts.Interval
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", r.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat %s: %w", r.path, err)
+	}
+	r.f, r.size, r.openedAt = f, info.Size(), r.now()
+	return nil
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", r.path, err)
+	}
+	r.f = nil
+	rotated := r.path + "." + r.now().UTC().Format(rotatedTimeFormat)
+	for i := 1; exists(rotated) || exists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", r.path, r.now().UTC().Format(rotatedTimeFormat), i)
+	}
+	if err := os.Rename(r.path, rotated); err != nil {
+		return fmt.Errorf("rotate %s: %w", r.path, err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if r.opts.Compress {
+			if err := compressFile(rotated); err != nil {
+				r.log.Error("ndjson: compress rotated file", "path", rotated, "error", err)
+			}
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// prune deletes the oldest rotated files beyond MaxFiles.
+func (r *RotatingFile) prune() {
+	if r.opts.MaxFiles <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+	var rotated []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, ".tmp") {
+			rotated = append(rotated, m)
+		}
+	}
+	// The timestamp suffix sorts chronologically.
+	sort.Strings(rotated)
+	for len(rotated) > r.opts.MaxFiles {
+		if err := os.Remove(rotated[0]); err != nil && !os.IsNotExist(err) {
+			r.log.Error("ndjson: remove old file", "path", rotated[0], "error", err)
+		}
+		rotated = rotated[1:]
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,80 @@
+// Package ndjson writes Faro items as newline-delimited JSON to stdout or rotated files.
+package ndjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+// line is one NDJSON record: the item as the other sinks would see it.
+type line struct {
+	Time      string                 `json:"time"`
+	Tenant    string                 `json:"tenant"`
+	Kind      string                 `json:"kind"`
+	Timestamp string                 `json:"timestamp,omitempty"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// Sink writes one JSON line per item. Each Write is flushed in a single call so lines
+// from concurrent writers never interleave.
+type Sink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// New writes to w (e.g. os.Stdout).
+func New(w io.Writer) *Sink {
+	return &Sink{w: w, now: time.Now}
+}
+
+// Write implements usecase.Sink.
+func (s *Sink) Write(_ context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, it := range items {
+		err := enc.Encode(line{
+			Time:      now,
+			Tenant:    tenantID,
+			Kind:      it.Kind,
+			Timestamp: it.Timestamp,
+			Level:     it.Level(),
+			Message:   it.Message(),
+			Fields:    it.AllFields(),
+		})
+		if err != nil {
+			return fmt.Errorf("encode item: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying writer when it is a rotating file.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(*RotatingFile); ok {
+		return c.Close()
+	}
+	return nil
+}
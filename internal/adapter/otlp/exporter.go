@@ -0,0 +1,108 @@
+// Package otlp exports Faro items as OpenTelemetry logs, and the collector's own spans as traces, over OTLP/HTTP.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+// Protocols accepted by NewExporter (same values as OTEL_EXPORTER_OTLP_PROTOCOL).
+const (
+	ProtocolProtobuf = "http/protobuf"
+	ProtocolJSON     = "http/json"
+)
+
+const (
+	defaultTimeout = 15 * time.Second
+	logsPath       = "/v1/logs"
+	tracesPath     = "/v1/traces"
+)
+
+// Exporter sends items to an OTLP/HTTP logs endpoint (e.g. an OpenTelemetry Collector).
+type Exporter struct {
+	url        string
+	protocol   string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewExporter creates an exporter. endpoint is the collector base URL (e.g. http://otel-collector:4318);
+// /v1/logs is appended when it has no path. headers are sent with every request (e.g. authorization).
+func NewExporter(endpoint, protocol string, headers map[string]string, timeout time.Duration) *Exporter {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if protocol == "" {
+		protocol = ProtocolProtobuf
+	}
+	return &Exporter{
+		url:        withPath(endpoint, logsPath),
+		protocol:   protocol,
+		headers:    headers,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Write implements usecase.Sink.
+func (e *Exporter) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	req := buildRequest(items, time.Now())
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	if e.protocol == ProtocolJSON {
+		body, err = json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("marshal payload: %w", err)
+		}
+		contentType = "application/json"
+	} else {
+		body = req.marshalProto()
+		contentType = "application/x-protobuf"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("X-Scope-OrgID", tenantID)
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode/100 == 4 {
+			return fmt.Errorf("%w: otlp returned %d: %s", domain.ErrSinkRejected, resp.StatusCode, string(b))
+		}
+		return fmt.Errorf("otlp returned %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// withPath appends path to endpoint when it has none.
+func withPath(endpoint, path string) string {
+	if u, err := url.Parse(endpoint); err == nil && (u.Path == "" || u.Path == "/") {
+		return strings.TrimSuffix(endpoint, "/") + path
+	}
+	return endpoint
+}
@@ -0,0 +1,303 @@
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Minimal OTLP logs model (opentelemetry/proto/collector/logs/v1). The JSON tags follow the
+// OTLP/JSON mapping; marshalProto writes the same message in protobuf wire format.
+type exportLogsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type logRecord struct {
+	TimeUnixNano         uint64     `json:"timeUnixNano,string"`
+	ObservedTimeUnixNano uint64     `json:"observedTimeUnixNano,string"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue holds exactly one of its fields.
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *int64   `json:"intValue,omitempty,string"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+const scopeName = "faro-web-sdk"
+
+// Item fields renamed to OpenTelemetry semantic conventions; message and level become body and severity.
+var attributeNames = map[string]string{
+	"event_name":           "event.name",
+	"event_domain":         "event.domain",
+	"exception_type":       "exception.type",
+	"exception_value":      "exception.message",
+	"exception_stacktrace": "exception.stacktrace",
+}
+
+// buildRequest groups items into one ResourceLogs per payload Meta.
+func buildRequest(items []domain.Item, now time.Time) exportLogsRequest {
+	var req exportLogsRequest
+	var meta *domain.Meta
+	for _, it := range items {
+		if it.Meta != meta || len(req.ResourceLogs) == 0 {
+			meta = it.Meta
+			m := meta
+			if m == nil {
+				m = &domain.Meta{}
+			}
+			req.ResourceLogs = append(req.ResourceLogs, resourceLogs{
+				Resource:  resource{Attributes: resourceAttributes(m)},
+				ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName, Version: m.SDK.Version}}},
+			})
+		}
+		sl := &req.ResourceLogs[len(req.ResourceLogs)-1].ScopeLogs[0]
+		sl.LogRecords = append(sl.LogRecords, toLogRecord(it, now))
+	}
+	return req
+}
+
+func resourceAttributes(m *domain.Meta) []keyValue {
+	var attrs []keyValue
+	addString := func(k, v string) {
+		if v != "" {
+			attrs = append(attrs, keyValue{Key: k, Value: stringValue(v)})
+		}
+	}
+	serviceName := m.App.Name
+	if serviceName == "" {
+		serviceName = "unknown_service"
+	}
+	addString("service.name", serviceName)
+	addString("service.version", m.App.Version)
+	addString("deployment.environment", m.App.Environment)
+	addString("browser.name", m.Browser.Name)
+	addString("browser.version", m.Browser.Version)
+	addString("browser.platform", m.Browser.OS)
+	mobile := m.Browser.Mobile
+	attrs = append(attrs, keyValue{Key: "browser.mobile", Value: anyValue{BoolValue: &mobile}})
+	addString("telemetry.sdk.name", "faro")
+	addString("telemetry.sdk.language", "webjs")
+	addString("telemetry.sdk.version", m.SDK.Version)
+	return attrs
+}
+
+func toLogRecord(it domain.Item, now time.Time) logRecord {
+	ts := now
+	if t, err := time.Parse(time.RFC3339Nano, it.Timestamp); err == nil {
+		ts = t
+	}
+	level := it.Level()
+	rec := logRecord{
+		TimeUnixNano:         uint64(ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       severityNumber(level),
+		SeverityText:         level,
+		Body:                 stringValue(it.Message()),
+	}
+	rec.Attributes = append(rec.Attributes, keyValue{Key: "faro.kind", Value: stringValue(it.Kind)})
+	if m := it.Meta; m != nil {
+		for _, kv := range [][2]string{
+			{"session.id", m.Session.ID},
+			{"url.full", m.Page.URL},
+			{"faro.view.name", m.View.Name},
+			{"user.name", m.User.Username},
+		} {
+			if kv[1] != "" {
+				rec.Attributes = append(rec.Attributes, keyValue{Key: kv[0], Value: stringValue(kv[1])})
+			}
+		}
+		for _, k := range sortedKeys(m.User.Attributes) {
+			rec.Attributes = append(rec.Attributes, keyValue{Key: "user.attributes." + k, Value: toAnyValue(m.User.Attributes[k])})
+		}
+	}
+	for _, k := range sortedKeys(it.Fields) {
+		if k == "kind" || k == "level" || k == "message" {
+			continue
+		}
+		name := k
+		if n, ok := attributeNames[k]; ok {
+			name = n
+		}
+		rec.Attributes = append(rec.Attributes, keyValue{Key: name, Value: toAnyValue(it.Fields[k])})
+	}
+	return rec
+}
+
+// severityNumber maps Faro log levels to OpenTelemetry SeverityNumber.
+func severityNumber(level string) int {
+	switch level {
+	case "trace":
+		return 1
+	case "debug":
+		return 5
+	case "warn", "warning":
+		return 13
+	case "error":
+		return 17
+	default:
+		return 9
+	}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+// toAnyValue keeps scalars typed and encodes anything else as JSON text.
+func toAnyValue(v interface{}) anyValue {
+	switch val := v.(type) {
+	case string:
+		return stringValue(val)
+	case bool:
+		return anyValue{BoolValue: &val}
+	case float64:
+		return anyValue{DoubleValue: &val}
+	case int:
+		n := int64(val)
+		return anyValue{IntValue: &n}
+	case int64:
+		return anyValue{IntValue: &val}
+	case nil:
+		return stringValue("")
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return stringValue(fmt.Sprint(val))
+		}
+		return stringValue(string(b))
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// marshalProto encodes ExportLogsServiceRequest:
+//
+//	ExportLogsServiceRequest { repeated ResourceLogs resource_logs = 1; }
+//	ResourceLogs { Resource resource = 1; repeated ScopeLogs scope_logs = 2; }
+//	Resource     { repeated KeyValue attributes = 1; }
+//	ScopeLogs    { InstrumentationScope scope = 1; repeated LogRecord log_records = 2; }
+//	InstrumentationScope { string name = 1; string version = 2; }
+//	LogRecord    { fixed64 time_unix_nano = 1; SeverityNumber severity_number = 2; string severity_text = 3;
+//	               AnyValue body = 5; repeated KeyValue attributes = 6; fixed64 observed_time_unix_nano = 11; }
+//	KeyValue     { string key = 1; AnyValue value = 2; }
+//	AnyValue     { oneof { string string_value = 1; bool bool_value = 2; int64 int_value = 3; double double_value = 4; } }
+func (r exportLogsRequest) marshalProto() []byte {
+	var b []byte
+	for _, rl := range r.ResourceLogs {
+		var rlb []byte
+		var res []byte
+		for _, kv := range rl.Resource.Attributes {
+			res = appendMessage(res, 1, kv.marshalProto())
+		}
+		rlb = appendMessage(rlb, 1, res)
+		for _, sl := range rl.ScopeLogs {
+			var slb, sc []byte
+			sc = appendString(sc, 1, sl.Scope.Name)
+			sc = appendString(sc, 2, sl.Scope.Version)
+			slb = appendMessage(slb, 1, sc)
+			for _, rec := range sl.LogRecords {
+				slb = appendMessage(slb, 2, rec.marshalProto())
+			}
+			rlb = appendMessage(rlb, 2, slb)
+		}
+		b = appendMessage(b, 1, rlb)
+	}
+	return b
+}
+
+func (rec logRecord) marshalProto() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, rec.TimeUnixNano)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(rec.SeverityNumber))
+	b = appendString(b, 3, rec.SeverityText)
+	b = appendMessage(b, 5, rec.Body.marshalProto())
+	for _, kv := range rec.Attributes {
+		b = appendMessage(b, 6, kv.marshalProto())
+	}
+	b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, rec.ObservedTimeUnixNano)
+	return b
+}
+
+func (kv keyValue) marshalProto() []byte {
+	var b []byte
+	b = appendString(b, 1, kv.Key)
+	return appendMessage(b, 2, kv.Value.marshalProto())
+}
+
+func (v anyValue) marshalProto() []byte {
+	var b []byte
+	switch {
+	case v.StringValue != nil:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, *v.StringValue)
+	case v.BoolValue != nil:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*v.BoolValue))
+	case v.IntValue != nil:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(*v.DoubleValue))
+	}
+	return b
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
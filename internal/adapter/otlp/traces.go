@@ -0,0 +1,124 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"collector-fe-instrumentation/internal/tracing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const traceScopeName = "collector-fe-instrumentation"
+
+// TraceExporter sends the collector's spans to an OTLP/HTTP traces endpoint as protobuf.
+type TraceExporter struct {
+	url        string
+	headers    map[string]string
+	resource   []keyValue
+	httpClient *http.Client
+}
+
+// NewTraceExporter creates a tracing.Exporter. endpoint is the collector base URL; /v1/traces is
+// appended when it has no path. serviceName becomes the service.name resource attribute.
+func NewTraceExporter(endpoint, serviceName string, headers map[string]string, timeout time.Duration) *TraceExporter {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &TraceExporter{
+		url:     withPath(endpoint, tracesPath),
+		headers: headers,
+		resource: []keyValue{
+			{Key: "service.name", Value: stringValue(serviceName)},
+			{Key: "telemetry.sdk.language", Value: stringValue("go")},
+		},
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// ExportSpans implements tracing.Exporter.
+func (e *TraceExporter) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(marshalTraces(e.resource, spans)))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("otlp returned %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// marshalTraces encodes ExportTraceServiceRequest with a single resource and scope:
+//
+//	ExportTraceServiceRequest { repeated ResourceSpans resource_spans = 1; }
+//	ResourceSpans { Resource resource = 1; repeated ScopeSpans scope_spans = 2; }
+//	ScopeSpans    { InstrumentationScope scope = 1; repeated Span spans = 2; }
+//	Span          { bytes trace_id = 1; bytes span_id = 2; bytes parent_span_id = 4; string name = 5; SpanKind kind = 6;
+//	                fixed64 start_time_unix_nano = 7; fixed64 end_time_unix_nano = 8; repeated KeyValue attributes = 9;
+//	                Status status = 15; fixed32 flags = 16; }
+//	Status        { string message = 2; StatusCode code = 3; }
+func marshalTraces(resource []keyValue, spans []tracing.SpanData) []byte {
+	var res, sc, ss []byte
+	for _, kv := range resource {
+		res = appendMessage(res, 1, kv.marshalProto())
+	}
+	sc = appendString(sc, 1, traceScopeName)
+	ss = appendMessage(ss, 1, sc)
+	for _, s := range spans {
+		ss = appendMessage(ss, 2, marshalSpan(s))
+	}
+	var rs []byte
+	rs = appendMessage(rs, 1, res)
+	rs = appendMessage(rs, 2, ss)
+	return appendMessage(nil, 1, rs)
+}
+
+func marshalSpan(s tracing.SpanData) []byte {
+	var b []byte
+	b = appendMessage(b, 1, s.TraceID[:])
+	b = appendMessage(b, 2, s.SpanID[:])
+	if s.Parent != (tracing.SpanID{}) {
+		b = appendMessage(b, 4, s.Parent[:])
+	}
+	b = appendString(b, 5, s.Name)
+	b = protowire.AppendTag(b, 6, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(s.Kind))
+	b = protowire.AppendTag(b, 7, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(s.Start.UnixNano()))
+	b = protowire.AppendTag(b, 8, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(s.End.UnixNano()))
+	for _, a := range s.Attributes {
+		b = appendMessage(b, 9, keyValue{Key: a.Key, Value: toAnyValue(a.Value)}.marshalProto())
+	}
+	if s.Error != "" {
+		var st []byte
+		st = appendString(st, 2, s.Error)
+		st = protowire.AppendTag(st, 3, protowire.VarintType)
+		st = protowire.AppendVarint(st, 2) // STATUS_CODE_ERROR
+		b = appendMessage(b, 15, st)
+	}
+	var flags uint32
+	if s.Sampled {
+		flags = 1
+	}
+	b = protowire.AppendTag(b, 16, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, flags)
+}
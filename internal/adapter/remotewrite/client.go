@@ -0,0 +1,152 @@
+// Package remotewrite pushes derived frontend metrics with the Prometheus remote-write protocol.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultTimeout   = 15 * time.Second
+	defaultBaseDelay = 500 * time.Millisecond
+	maxDelay         = 10 * time.Second
+)
+
+// Label is a metric label; series labels must include __name__.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value at a millisecond timestamp.
+type Sample struct {
+	Value       float64
+	TimestampMs int64
+}
+
+// TimeSeries is a labeled set of samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Client sends remote-write requests (snappy-compressed protobuf) for one tenant at a time.
+type Client struct {
+	url        string
+	token      string
+	maxRetries int
+	baseDelay  time.Duration
+	httpClient *http.Client
+}
+
+// NewClient creates a remote-write client. url is the full write endpoint (e.g. https://mimir/api/v1/push).
+func NewClient(url, token string, timeout time.Duration, maxRetries int) *Client {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{
+		url:        url,
+		token:      token,
+		maxRetries: maxRetries,
+		baseDelay:  defaultBaseDelay,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Write sends series for tenantID (X-Scope-OrgID), retrying network errors, 429 and 5xx with backoff.
+func (c *Client) Write(ctx context.Context, tenantID string, series []TimeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+	body := snappy.Encode(nil, marshalWriteRequest(series))
+
+	var err error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := c.baseDelay << (attempt - 1)
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		var retry bool
+		retry, err = c.send(ctx, tenantID, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, tenantID string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("X-Scope-OrgID", tenantID)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("remote write returned %d: %s", resp.StatusCode, string(b))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// marshalWriteRequest encodes prometheus.WriteRequest:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func marshalWriteRequest(series []TimeSeries) []byte {
+	var out []byte
+	for _, ts := range series {
+		var tsb []byte
+		for _, l := range ts.Labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Value)
+			tsb = protowire.AppendTag(tsb, 1, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, lb)
+		}
+		for _, s := range ts.Samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(s.Value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(s.TimestampMs))
+			tsb = protowire.AppendTag(tsb, 2, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, sb)
+		}
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, tsb)
+	}
+	return out
+}
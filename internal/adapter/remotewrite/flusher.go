@@ -0,0 +1,136 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// TenantLabel is the label that selects the tenant a series is written for; it is not sent.
+const TenantLabel = "tenant"
+
+// Flusher periodically gathers metric families and writes them per tenant.
+type Flusher struct {
+	client   *Client
+	gatherer prometheus.Gatherer
+	prefix   string
+	interval time.Duration
+	log      *slog.Logger
+	now      func() time.Time
+}
+
+// NewFlusher creates a Flusher for the families of gatherer whose name starts with prefix.
+func NewFlusher(client *Client, gatherer prometheus.Gatherer, prefix string, interval time.Duration, log *slog.Logger) *Flusher {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Flusher{client: client, gatherer: gatherer, prefix: prefix, interval: interval, log: log, now: time.Now}
+}
+
+// Run flushes every interval until ctx is done.
+func (f *Flusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Flush(ctx); err != nil {
+				f.log.Error("remote write: flush failed", "error", err)
+			}
+		}
+	}
+}
+
+// Flush gathers the current values and writes one request per tenant.
+func (f *Flusher) Flush(ctx context.Context) error {
+	families, err := f.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gather: %w", err)
+	}
+	byTenant := seriesByTenant(families, f.prefix, f.now().UnixMilli())
+
+	tenants := make([]string, 0, len(byTenant))
+	for t := range byTenant {
+		tenants = append(tenants, t)
+	}
+	sort.Strings(tenants)
+
+	var errs []error
+	for _, tenant := range tenants {
+		if err := f.client.Write(ctx, tenant, byTenant[tenant]); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// seriesByTenant converts counters, gauges and histograms (as _bucket, _sum and _count) to series.
+func seriesByTenant(families []*dto.MetricFamily, prefix string, ts int64) map[string][]TimeSeries {
+	out := make(map[string][]TimeSeries)
+	for _, mf := range families {
+		name := mf.GetName()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			tenant, labels := splitTenant(m.GetLabel())
+			if tenant == "" {
+				continue
+			}
+			add := func(metric string, value float64, extra ...Label) {
+				ls := append([]Label{{Name: "__name__", Value: metric}}, labels...)
+				ls = append(ls, extra...)
+				sort.Slice(ls, func(i, j int) bool { return ls[i].Name < ls[j].Name })
+				out[tenant] = append(out[tenant], TimeSeries{Labels: ls, Samples: []Sample{{Value: value, TimestampMs: ts}}})
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						continue
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), Label{Name: "le", Value: formatFloat(b.GetUpperBound())})
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), Label{Name: "le", Value: "+Inf"})
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return out
+}
+
+// splitTenant returns the tenant label as is: it becomes the X-Scope-OrgID of the write, so the
+// producer must never fold it into an overflow value.
+func splitTenant(pairs []*dto.LabelPair) (string, []Label) {
+	var tenant string
+	labels := make([]Label, 0, len(pairs))
+	for _, p := range pairs {
+		if p.GetName() == TenantLabel {
+			tenant = p.GetValue()
+			continue
+		}
+		labels = append(labels, Label{Name: p.GetName(), Value: p.GetValue()})
+	}
+	return tenant, labels
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
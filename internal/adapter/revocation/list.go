@@ -0,0 +1,179 @@
+// Package revocation holds the set of revoked collector tokens, loaded from a file.
+package revocation
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	prefixJTI    = "jti:"
+	prefixSHA256 = "sha256:"
+)
+
+// List is a set of revoked tokens keyed by jti or by the SHA-256 of the raw token.
+// Entries are stored as SHA-256 digests so lookups never compare secret material directly.
+type List struct {
+	path    string
+	log     *slog.Logger
+	entries atomic.Pointer[map[[sha256.Size]byte]struct{}]
+
+	mu      sync.Mutex // serializes reloads
+	modTime time.Time
+	lastErr error
+}
+
+// Load reads the revocation file at path. The file holds one entry per line:
+// "jti:<id>" or "sha256:<hex digest of the token>". Blank lines and lines starting with # are ignored.
+func Load(path string, log *slog.Logger) (*List, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	l := &List{path: path, log: log}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload re-reads the file and swaps the entry set. On error the previous set is kept.
+func (l *List) Reload() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n, err := l.reload()
+	l.lastErr = err
+	return n, err
+}
+
+func (l *List) reload() (int, error) {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return 0, fmt.Errorf("stat revocation file: %w", err)
+	}
+	entries, err := parseFile(l.path)
+	if err != nil {
+		return 0, err
+	}
+	l.entries.Store(&entries)
+	l.modTime = info.ModTime()
+	return len(entries), nil
+}
+
+// Err returns the error of the last reload, or nil when the loaded list is current.
+func (l *List) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastErr
+}
+
+// Watch polls the file every interval and reloads it when its modification time changes.
+// It returns when ctx is done.
+func (l *List) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(l.path)
+			if err != nil {
+				l.log.Warn("revocation: stat failed", "path", l.path, "error", err)
+				continue
+			}
+			l.mu.Lock()
+			changed := !info.ModTime().Equal(l.modTime)
+			l.mu.Unlock()
+			if !changed {
+				continue
+			}
+			n, err := l.Reload()
+			if err != nil {
+				l.log.Error("revocation: reload failed, keeping previous list", "path", l.path, "error", err)
+				continue
+			}
+			l.log.Info("revocation: list reloaded", "path", l.path, "entries", n)
+		}
+	}
+}
+
+// IsRevoked reports whether the token, or its jti when non-empty, is on the list.
+func (l *List) IsRevoked(jti, token string) bool {
+	entries := l.entries.Load()
+	if entries == nil {
+		return false
+	}
+	if jti != "" {
+		if _, ok := (*entries)[sha256.Sum256([]byte(prefixJTI+jti))]; ok {
+			return true
+		}
+	}
+	_, ok := (*entries)[sha256.Sum256([]byte(token))]
+	return ok
+}
+
+// Len returns the number of entries currently loaded.
+func (l *List) Len() int {
+	entries := l.entries.Load()
+	if entries == nil {
+		return 0
+	}
+	return len(*entries)
+}
+
+// HashToken returns the "sha256:<hex>" entry that revokes the given raw token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return prefixSHA256 + hex.EncodeToString(sum[:])
+}
+
+func parseFile(path string) (map[[sha256.Size]byte]struct{}, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from operator config
+	if err != nil {
+		return nil, fmt.Errorf("open revocation file: %w", err)
+	}
+	defer f.Close()
+
+	entries := make(map[[sha256.Size]byte]struct{})
+	sc := bufio.NewScanner(f)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, prefixJTI):
+			id := strings.TrimSpace(strings.TrimPrefix(line, prefixJTI))
+			if id == "" {
+				return nil, fmt.Errorf("revocation file line %d: empty jti", lineNo)
+			}
+			entries[sha256.Sum256([]byte(prefixJTI+id))] = struct{}{}
+		case strings.HasPrefix(line, prefixSHA256):
+			raw, err := hex.DecodeString(strings.TrimSpace(strings.TrimPrefix(line, prefixSHA256)))
+			if err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("revocation file line %d: invalid sha256 digest", lineNo)
+			}
+			var key [sha256.Size]byte
+			copy(key[:], raw)
+			entries[key] = struct{}{}
+		default:
+			return nil, fmt.Errorf("revocation file line %d: expected jti: or sha256: prefix", lineNo)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read revocation file: %w", err)
+	}
+	return entries, nil
+}
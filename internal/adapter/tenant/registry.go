@@ -0,0 +1,290 @@
+// Package tenant holds the registry of known tenants and their sink endpoints, loaded from a file.
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Auth types accepted in a tenant's loki.auth.type.
+const (
+	AuthNone   = "none"
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+)
+
+// Tenant is one registry entry.
+type Tenant struct {
+	Loki Loki `json:"loki"`
+}
+
+// Loki is a tenant's Loki endpoint. Empty fields fall back to the global LOKI_* settings.
+type Loki struct {
+	URL string `json:"url"`
+	// OrgID is sent as X-Scope-OrgID instead of the tenant name.
+	OrgID   string            `json:"org_id"`
+	Auth    *Auth             `json:"auth"`
+	Headers map[string]string `json:"headers"`
+	Timeout Duration          `json:"timeout"`
+}
+
+// Auth holds a tenant's Loki credentials. Values may reference environment variables as ${NAME}.
+type Auth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("10s") in the file.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// file is the on-disk layout: {"tenants": {"<name>": {...}}}.
+type file struct {
+	Tenants map[string]Tenant `json:"tenants"`
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// RequireLokiURL rejects files with a tenant that has no loki.url, for when there is no global
+// LOKI_URL to fall back to.
+func RequireLokiURL() Option {
+	return func(r *Registry) {
+		r.requireURL = true
+	}
+}
+
+// Registry maps tenant names to their settings. Tenants not in the file are unknown.
+type Registry struct {
+	path       string
+	log        *slog.Logger
+	requireURL bool
+	tenants    atomic.Pointer[map[string]Tenant]
+
+	mu      sync.Mutex // serializes reloads
+	modTime time.Time
+	lastErr error
+}
+
+// Load reads the registry file at path (JSON, see scripts/README.md for the layout).
+func Load(path string, log *slog.Logger, opts ...Option) (*Registry, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	r := &Registry{path: path, log: log}
+	for _, fn := range opts {
+		fn(r)
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the file and swaps the tenant set. On error the previous set is kept.
+func (r *Registry) Reload() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.reload()
+	r.lastErr = err
+	return n, err
+}
+
+func (r *Registry) reload() (int, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return 0, fmt.Errorf("stat tenants file: %w", err)
+	}
+	tenants, err := parseFile(r.path, r.requireURL)
+	if err != nil {
+		return 0, err
+	}
+	r.tenants.Store(&tenants)
+	r.modTime = info.ModTime()
+	return len(tenants), nil
+}
+
+// Err returns the error of the last reload, or nil when the loaded registry is current.
+func (r *Registry) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Watch polls the file every interval and reloads it when its modification time changes.
+// It returns when ctx is done.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			if err != nil {
+				r.log.Warn("tenants: stat failed", "path", r.path, "error", err)
+				continue
+			}
+			r.mu.Lock()
+			changed := !info.ModTime().Equal(r.modTime)
+			r.mu.Unlock()
+			if !changed {
+				continue
+			}
+			n, err := r.Reload()
+			if err != nil {
+				r.log.Error("tenants: reload failed, keeping previous registry", "path", r.path, "error", err)
+				continue
+			}
+			r.log.Info("tenants: registry reloaded", "path", r.path, "tenants", n)
+		}
+	}
+}
+
+// Lookup returns the tenant's settings and whether it is registered.
+func (r *Registry) Lookup(name string) (Tenant, bool) {
+	tenants := r.tenants.Load()
+	if tenants == nil {
+		return Tenant{}, false
+	}
+	t, ok := (*tenants)[name]
+	return t, ok
+}
+
+// Has reports whether the tenant is registered.
+func (r *Registry) Has(name string) bool {
+	_, ok := r.Lookup(name)
+	return ok
+}
+
+// Names returns the registered tenants, sorted.
+func (r *Registry) Names() []string {
+	tenants := r.tenants.Load()
+	if tenants == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*tenants))
+	for name := range *tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseFile(path string, requireURL bool) (map[string]Tenant, error) {
+	b, err := os.ReadFile(path) // #nosec G304 -- path comes from operator config
+	if err != nil {
+		return nil, fmt.Errorf("read tenants file: %w", err)
+	}
+	var f file
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("parse tenants file: %w", err)
+	}
+	if f.Tenants == nil {
+		return nil, fmt.Errorf("tenants file: missing \"tenants\" object")
+	}
+	for name, t := range f.Tenants {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("tenants file: empty tenant name")
+		}
+		if err := t.Loki.validate(); err != nil {
+			return nil, fmt.Errorf("tenants file: tenant %q: %w", name, err)
+		}
+		if requireURL && t.Loki.URL == "" {
+			return nil, fmt.Errorf("tenants file: tenant %q: loki.url is required when LOKI_URL is not set", name)
+		}
+		f.Tenants[name] = t
+	}
+	return f.Tenants, nil
+}
+
+// envRef matches a ${NAME} reference; a bare "$" is kept as is, so literal values like "pa$$w0rd" survive.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} references with the environment; an unset variable is an error.
+func expandEnv(v string) (string, error) {
+	var missing []string
+	out := envRef.ReplaceAllStringFunc(v, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return val
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// validate checks the endpoint and expands ${NAME} references in credentials and headers.
+func (l *Loki) validate() error {
+	if l.URL != "" && !strings.HasPrefix(l.URL, "http://") && !strings.HasPrefix(l.URL, "https://") {
+		return fmt.Errorf("loki.url must be http(s)")
+	}
+	for k, v := range l.Headers {
+		expanded, err := expandEnv(v)
+		if err != nil {
+			return fmt.Errorf("loki.headers.%s: %w", k, err)
+		}
+		l.Headers[k] = expanded
+	}
+	if l.Auth == nil {
+		return nil
+	}
+	a := l.Auth
+	for _, f := range []struct {
+		name string
+		v    *string
+	}{{"token", &a.Token}, {"username", &a.Username}, {"password", &a.Password}} {
+		expanded, err := expandEnv(*f.v)
+		if err != nil {
+			return fmt.Errorf("loki.auth.%s: %w", f.name, err)
+		}
+		*f.v = expanded
+	}
+	switch a.Type {
+	case AuthNone:
+	case AuthBearer:
+		if a.Token == "" {
+			return fmt.Errorf("loki.auth: bearer needs token")
+		}
+	case AuthBasic:
+		if a.Username == "" {
+			return fmt.Errorf("loki.auth: basic needs username")
+		}
+	default:
+		return fmt.Errorf("loki.auth.type must be none, bearer or basic")
+	}
+	return nil
+}
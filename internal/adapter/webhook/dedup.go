@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Alert reasons.
+const (
+	ReasonNew   = "new"
+	ReasonSpike = "spike"
+)
+
+const maxFingerprints = 10000
+
+// tracker decides which exception occurrences raise an alert: the first one of a fingerprint
+// not seen for a window ("new"), and the one reaching the spike threshold within a window ("spike").
+type tracker struct {
+	window    time.Duration
+	threshold int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru orders the states by lastSeen, most recent first.
+	lru *list.List
+}
+
+type fingerprintState struct {
+	fingerprint  string
+	lastSeen     time.Time
+	windowStart  time.Time
+	count        int
+	spikeAlerted bool
+}
+
+func newTracker(window time.Duration, threshold int) *tracker {
+	return &tracker{window: window, threshold: threshold, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// observe records one occurrence and returns the alert reason, or "" when it is deduplicated.
+// count is the number of occurrences in the current window.
+func (t *tracker) observe(fingerprint string, now time.Time) (reason string, count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	el, ok := t.entries[fingerprint]
+	if !ok {
+		if len(t.entries) >= maxFingerprints {
+			t.evict()
+		}
+		el = t.lru.PushFront(&fingerprintState{fingerprint: fingerprint, lastSeen: now, windowStart: now, count: 1})
+		t.entries[fingerprint] = el
+		return ReasonNew, 1
+	}
+	t.lru.MoveToFront(el)
+	st := el.Value.(*fingerprintState)
+	if now.Sub(st.lastSeen) > t.window {
+		*st = fingerprintState{fingerprint: fingerprint, lastSeen: now, windowStart: now, count: 1}
+		return ReasonNew, 1
+	}
+	st.lastSeen = now
+	if now.Sub(st.windowStart) > t.window {
+		st.windowStart, st.count, st.spikeAlerted = now, 0, false
+	}
+	st.count++
+	if t.threshold > 0 && st.count >= t.threshold && !st.spikeAlerted {
+		st.spikeAlerted = true
+		return ReasonSpike, st.count
+	}
+	return "", st.count
+}
+
+// forget undoes the alert decision observe returned as reason for an alert that was not delivered
+// (over the per-minute limit or failed), so the next occurrence raises it again.
+func (t *tracker) forget(fingerprint, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	el, ok := t.entries[fingerprint]
+	if !ok {
+		return
+	}
+	switch reason {
+	case ReasonNew:
+		t.lru.Remove(el)
+		delete(t.entries, fingerprint)
+	case ReasonSpike:
+		el.Value.(*fingerprintState).spikeAlerted = false
+	}
+}
+
+// evict drops the least recently seen fingerprint.
+func (t *tracker) evict() {
+	if el := t.lru.Back(); el != nil {
+		t.lru.Remove(el)
+		delete(t.entries, el.Value.(*fingerprintState).fingerprint)
+	}
+}
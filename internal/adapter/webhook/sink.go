@@ -0,0 +1,272 @@
+// Package webhook posts alerts for new or spiking exception fingerprints to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	defaultWindow    = time.Hour
+	defaultBaseDelay = 500 * time.Millisecond
+	maxDelay         = 10 * time.Second
+
+	// HeaderSignature carries "sha256=<hex>" of HMAC-SHA256(secret, timestamp + "." + body).
+	HeaderSignature = "X-Faro-Signature-256"
+	// HeaderTimestamp is the Unix time included in the signature.
+	HeaderTimestamp = "X-Faro-Timestamp"
+)
+
+// Options configures the Sink.
+type Options struct {
+	URL      string
+	Template *template.Template
+	// Secret enables HMAC signing of each request.
+	Secret string
+	// Window is the deduplication window per fingerprint (default 1h).
+	Window time.Duration
+	// SpikeThreshold alerts again when a fingerprint reaches this many occurrences in a window; 0 disables.
+	SpikeThreshold int
+	// MaxAlertsPerMinute caps the alerts sent across all fingerprints; later ones in the same minute
+	// are dropped. 0 disables the cap.
+	MaxAlertsPerMinute int
+	Timeout            time.Duration
+	MaxRetries         int
+}
+
+// Sink turns exception items into webhook alerts; other kinds are ignored.
+type Sink struct {
+	opts       Options
+	tracker    *tracker
+	budget     *alertBudget
+	baseDelay  time.Duration
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// New creates a webhook sink. opts.Template defaults to the generic JSON body.
+func New(opts Options) (*Sink, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Window == 0 {
+		opts.Window = defaultWindow
+	}
+	if opts.Template == nil {
+		t, err := ParseTemplate(FormatJSON, "")
+		if err != nil {
+			return nil, err
+		}
+		opts.Template = t
+	}
+	return &Sink{
+		opts:       opts,
+		tracker:    newTracker(opts.Window, opts.SpikeThreshold),
+		budget:     &alertBudget{max: opts.MaxAlertsPerMinute},
+		baseDelay:  defaultBaseDelay,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		now:        time.Now,
+	}, nil
+}
+
+// Write implements usecase.Sink.
+func (s *Sink) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	var errs []error
+	for _, it := range items {
+		if it.Kind != domain.KindException {
+			continue
+		}
+		now := s.now()
+		alert := s.alertFor(tenantID, it, now)
+		reason, count := s.tracker.observe(alert.Fingerprint, now)
+		if reason == "" {
+			continue
+		}
+		if !s.budget.take(now) {
+			s.tracker.forget(alert.Fingerprint, reason)
+			continue
+		}
+		alert.Reason, alert.Count = reason, count
+		body, err := render(s.opts.Template, alert)
+		if err != nil {
+			s.tracker.forget(alert.Fingerprint, reason)
+			errs = append(errs, fmt.Errorf("render template: %w", err))
+			continue
+		}
+		if err := s.post(ctx, body, now); err != nil {
+			s.tracker.forget(alert.Fingerprint, reason)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sink) alertFor(tenantID string, it domain.Item, now time.Time) Alert {
+	str := func(k string) string {
+		v, _ := it.Fields[k].(string)
+		return v
+	}
+	a := Alert{
+		Tenant:     tenantID,
+		Type:       str("exception_type"),
+		Value:      str("exception_value"),
+		Stacktrace: str("exception_stacktrace"),
+		Window:     s.opts.Window,
+		Time:       now.UTC(),
+	}
+	if m := it.Meta; m != nil {
+		a.App, a.Environment = m.App.Name, m.App.Environment
+		a.PageURL, a.SessionID = m.Page.URL, m.Session.ID
+		a.Browser = strings.TrimSpace(m.Browser.Name + " " + m.Browser.Version)
+	}
+	a.Fingerprint = Fingerprint(tenantID, a.App, a.Type, a.Value, a.Stacktrace)
+	return a
+}
+
+// Fingerprint groups occurrences of the same exception: tenant, app, type, normalised message and top frame.
+func Fingerprint(tenant, app, typ, value, stacktrace string) string {
+	top, _, _ := strings.Cut(stacktrace, " | ")
+	h := sha256.New()
+	for _, part := range []string{tenant, app, typ, NormalizeMessage(value), top} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// messageVariables match the parts of exception messages that vary between occurrences of the
+// same error, in the order they are replaced.
+var messageVariables = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://\S+`), "<url>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]*(?:[0-9][0-9a-f]*[a-f]|[a-f][0-9a-f]*[0-9])[0-9a-f]*\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+}
+
+// maxNormalizedMessage bounds the message part of the fingerprint.
+const maxNormalizedMessage = 256
+
+// NormalizeMessage replaces URLs, UUIDs, hex identifiers and numbers in an exception message with
+// placeholders, so that e.g. "order 123 not found" and "order 456 not found" share a fingerprint.
+func NormalizeMessage(msg string) string {
+	for _, v := range messageVariables {
+		msg = v.re.ReplaceAllString(msg, v.placeholder)
+	}
+	if len(msg) > maxNormalizedMessage {
+		msg = msg[:maxNormalizedMessage]
+	}
+	return msg
+}
+
+// alertBudget caps the alerts sent per minute, so an error storm across many fingerprints
+// cannot flood the receiver.
+type alertBudget struct {
+	max int
+
+	mu          sync.Mutex
+	minuteStart time.Time
+	sent        int
+	suppressed  int
+}
+
+// take reports whether an alert may be sent at now and counts it.
+func (b *alertBudget) take(now time.Time) bool {
+	if b.max <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.minuteStart) >= time.Minute {
+		if b.suppressed > 0 {
+			slog.Warn("webhook alerts suppressed by the per-minute limit", "count", b.suppressed, "limit", b.max)
+		}
+		b.minuteStart, b.sent, b.suppressed = now, 0, 0
+	}
+	if b.sent >= b.max {
+		b.suppressed++
+		return false
+	}
+	b.sent++
+	return true
+}
+
+// post sends body, retrying network errors, 429 and 5xx with backoff.
+func (s *Sink) post(ctx context.Context, body []byte, now time.Time) error {
+	var err error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := s.baseDelay << (attempt - 1)
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		var retry bool
+		retry, err = s.send(ctx, body, now)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *Sink) send(ctx context.Context, body []byte, now time.Time) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Secret != "" {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, "sha256="+Sign(s.opts.Secret, ts, body))
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 == 4 {
+		err = fmt.Errorf("%w: webhook returned %d: %s", domain.ErrSinkRejected, resp.StatusCode, string(b))
+	} else {
+		err = fmt.Errorf("webhook returned %d: %s", resp.StatusCode, string(b))
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp + "." + body, as sent in X-Faro-Signature-256.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Built-in body formats.
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+)
+
+// Alert is the data available to body templates.
+type Alert struct {
+	Reason      string
+	Tenant      string
+	Fingerprint string
+	App         string
+	Environment string
+	Type        string
+	Value       string
+	Stacktrace  string
+	PageURL     string
+	SessionID   string
+	Browser     string
+	// Count is the number of occurrences of the fingerprint in the current window.
+	Count  int
+	Window time.Duration
+	Time   time.Time
+}
+
+var builtinTemplates = map[string]string{
+	FormatJSON: `{"reason":{{json .Reason}},"tenant":{{json .Tenant}},"fingerprint":{{json .Fingerprint}},` +
+		`"app":{{json .App}},"environment":{{json .Environment}},"type":{{json .Type}},"value":{{json .Value}},` +
+		`"stacktrace":{{json .Stacktrace}},"page_url":{{json .PageURL}},"session_id":{{json .SessionID}},` +
+		`"browser":{{json .Browser}},"count":{{.Count}},"window":{{json .Window.String}},"time":{{json .Time}}}`,
+	FormatSlack: `{"text":{{json (printf "%s exception in *%s* (%s, tenant %s): %s: %s — %d in %s\n%s" ` +
+		`(title .Reason) .App .Environment .Tenant .Type .Value .Count .Window .PageURL)}}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	// json renders v as a JSON value, so templates stay valid whatever the exception text contains.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+// ParseTemplate parses a body template; format selects a built-in one when text is empty.
+func ParseTemplate(format, text string) (*template.Template, error) {
+	if text == "" {
+		var ok bool
+		if text, ok = builtinTemplates[format]; !ok {
+			return nil, fmt.Errorf("unknown webhook format %q", format)
+		}
+	}
+	return template.New("webhook").Funcs(templateFuncs).Parse(text)
+}
+
+func render(t *template.Template, a Alert) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, a); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
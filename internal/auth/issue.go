@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IssueParams describes a collector token to mint.
+type IssueParams struct {
+	Tenant  string
+	Role    string
+	Issuer  string
+	TTL     time.Duration // 0 means no exp claim
+	Origins []string
+	ID      string // jti; generated when empty
+}
+
+// Issue signs a collector token with secretKey (HS256), the same key JWTAuth verifies with.
+func Issue(secretKey string, p IssueParams, now time.Time) (string, error) {
+	if secretKey == "" {
+		return "", errors.New("secret key is empty")
+	}
+	if p.Role == "" {
+		return "", errors.New("role is required")
+	}
+	p.Role = strings.ToLower(strings.TrimSpace(p.Role))
+	if !contains(AllowedRoles, p.Role) {
+		return "", fmt.Errorf("role %q is not accepted by /collect (allowed: %s)", p.Role, strings.Join(AllowedRoles, ", "))
+	}
+	if p.ID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		p.ID = hex.EncodeToString(b)
+	}
+	claims := jwt.MapClaims{
+		ClaimRole: p.Role,
+		"iss":     p.Issuer,
+		"iat":     now.Unix(),
+		"jti":     p.ID,
+	}
+	if p.TTL > 0 {
+		claims["exp"] = now.Add(p.TTL).Unix()
+	}
+	if p.Tenant != "" {
+		claims[ClaimTenant] = p.Tenant
+	}
+	if len(p.Origins) > 0 {
+		claims[ClaimOrigins] = p.Origins
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+}
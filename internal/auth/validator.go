@@ -0,0 +1,263 @@
+// Package auth validates and mints the JWTs that frontends use to call the collector.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"collector-fe-instrumentation/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claim names understood by the collector besides the registered ones.
+const (
+	ClaimRole    = "role"
+	ClaimTenant  = "tenant"
+	ClaimOrigins = "origins"
+)
+
+// AllowedRoles are the role claim values accepted on /collect.
+var AllowedRoles = []string{"admin", "user"}
+
+// Reason identifies which check rejected a token.
+type Reason string
+
+const (
+	ReasonMissing          Reason = "token_missing"
+	ReasonInvalid          Reason = "token_invalid"
+	ReasonExpired          Reason = "token_expired"
+	ReasonRevoked          Reason = "token_revoked"
+	ReasonMissingIAT       Reason = "missing_iat"
+	ReasonTooOld           Reason = "token_too_old"
+	ReasonMissingRole      Reason = "missing_role"
+	ReasonInsufficientRole Reason = "insufficient_role"
+	ReasonInvalidIssuer    Reason = "invalid_issuer"
+	ReasonTenantMismatch   Reason = "tenant_mismatch"
+	ReasonOriginMismatch   Reason = "origin_mismatch"
+)
+
+// Error is returned by Validate; Detail is meant for logs and operators, never for clients.
+type Error struct {
+	Reason Reason
+	Detail string
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return string(e.Reason)
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Detail)
+}
+
+// Forbidden reports whether the token was authentic but not allowed (403 rather than 401).
+func (e *Error) Forbidden() bool {
+	switch e.Reason {
+	case ReasonMissingRole, ReasonInsufficientRole, ReasonInvalidIssuer, ReasonTenantMismatch, ReasonOriginMismatch:
+		return true
+	}
+	return false
+}
+
+// RevocationChecker reports whether a token has been revoked, by jti or by the raw token.
+type RevocationChecker interface {
+	IsRevoked(jti, token string) bool
+}
+
+// Request carries the request context a token may be bound to.
+type Request struct {
+	Tenant string
+	Origin string
+}
+
+// Validator runs every check JWTAuth applies to a collector token.
+type Validator struct {
+	secretKey      []byte
+	parser         *jwt.Parser
+	expectedIssuer string
+	maxTokenAge    time.Duration
+	leeway         time.Duration
+	revocations    RevocationChecker
+	bindClaims     bool
+}
+
+// NewValidator builds a Validator from config; revocations may be nil.
+func NewValidator(cfg *config.Config, revocations RevocationChecker) *Validator {
+	return &Validator{
+		secretKey:      []byte(cfg.SecretKey),
+		parser:         jwt.NewParser(parserOptions(cfg)...),
+		expectedIssuer: cfg.JWTIssuer,
+		maxTokenAge:    cfg.JWTMaxTokenAge,
+		leeway:         cfg.JWTLeeway,
+		revocations:    revocations,
+		bindClaims:     cfg.JWTBindClaims,
+	}
+}
+
+// Validate parses the token and applies the checks in the same order as JWTAuth.
+// On failure the returned error is an *Error.
+func (v *Validator) Validate(tokenStr string, req Request) (jwt.MapClaims, error) {
+	if tokenStr == "" {
+		return nil, &Error{Reason: ReasonMissing}
+	}
+
+	token, err := v.parser.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return v.secretKey, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, &Error{Reason: ReasonExpired, Detail: err.Error()}
+		}
+		return nil, &Error{Reason: ReasonInvalid, Detail: err.Error()}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, &Error{Reason: ReasonInvalid, Detail: "invalid token claims"}
+	}
+
+	if v.revocations != nil {
+		jti, _ := claims["jti"].(string)
+		if v.revocations.IsRevoked(jti, tokenStr) {
+			return claims, &Error{Reason: ReasonRevoked, Detail: fmt.Sprintf("jti=%q", jti)}
+		}
+	}
+	if err := v.validateRole(claims); err != nil {
+		return claims, err
+	}
+	if v.maxTokenAge > 0 {
+		if err := v.validateTokenAge(claims); err != nil {
+			return claims, err
+		}
+	}
+	if v.bindClaims {
+		if err := validateBinding(claims, req); err != nil {
+			return claims, err
+		}
+	}
+	return claims, nil
+}
+
+// BindsClaims reports whether Validate enforces the tenant and origins claims (JWT_ENFORCE_BINDING).
+func (v *Validator) BindsClaims() bool {
+	return v.bindClaims
+}
+
+func (v *Validator) validateRole(claims jwt.MapClaims) error {
+	role, ok := claims[ClaimRole]
+	if !ok {
+		return &Error{Reason: ReasonMissingRole, Detail: "missing role in token"}
+	}
+	roleStr := strings.TrimSpace(strings.ToLower(fmt.Sprintf("%v", role)))
+	if !contains(AllowedRoles, roleStr) {
+		return &Error{Reason: ReasonInsufficientRole, Detail: fmt.Sprintf("role=%s", roleStr)}
+	}
+	// Compare as string (JWT claim can be string or other type)
+	if fmt.Sprint(claims["iss"]) != v.expectedIssuer {
+		return &Error{Reason: ReasonInvalidIssuer, Detail: fmt.Sprintf("issuer %v (expected %q)", claims["iss"], v.expectedIssuer)}
+	}
+	return nil
+}
+
+// validateTokenAge enforces JWT_MAX_TOKEN_AGE (plus leeway); tokens without iat cannot prove their age and are rejected.
+func (v *Validator) validateTokenAge(claims jwt.MapClaims) error {
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return &Error{Reason: ReasonMissingIAT, Detail: "missing iat with max token age policy"}
+	}
+	if time.Since(iat.Time) > v.maxTokenAge+v.leeway {
+		return &Error{Reason: ReasonTooOld, Detail: fmt.Sprintf("issued %s, max age %s", iat.Time.UTC().Format(time.RFC3339), v.maxTokenAge)}
+	}
+	return nil
+}
+
+// validateBinding checks the optional tenant and origins claims against the request.
+// Tokens without these claims are valid for any tenant and origin. Requests without an Origin header
+// (server-side senders) skip the origins check: only browsers send it, and other clients could forge it anyway.
+func validateBinding(claims jwt.MapClaims, req Request) error {
+	if tenant, ok := claims[ClaimTenant]; ok {
+		if fmt.Sprint(tenant) != req.Tenant {
+			return &Error{Reason: ReasonTenantMismatch, Detail: fmt.Sprintf("token tenant %v, request tenant %q", tenant, req.Tenant)}
+		}
+	}
+	if raw, ok := claims[ClaimOrigins]; ok && req.Origin != "" {
+		list, err := parseStrings(raw)
+		if err != nil {
+			return &Error{Reason: ReasonInvalid, Detail: "origins claim must be a string or array of strings"}
+		}
+		if !MatchOrigin(list, req.Origin) {
+			return &Error{Reason: ReasonOriginMismatch, Detail: fmt.Sprintf("origin %q not in %v", req.Origin, list)}
+		}
+	}
+	return nil
+}
+
+func parseStrings(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New("non-string entry")
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	case []string:
+		return v, nil
+	}
+	return nil, errors.New("unsupported type")
+}
+
+// MatchOrigin reports whether origin matches one of the allowed entries.
+// Entries are exact origins or "https://*.example.com" wildcards, as in ALLOW_ORIGINS.
+func MatchOrigin(allowed []string, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, a := range allowed {
+		if a == "*" || a == origin {
+			return true
+		}
+		if strings.HasPrefix(a, "https://*.") {
+			base := strings.TrimPrefix(a, "https://*")
+			if strings.HasPrefix(origin, "https://") && strings.HasSuffix(origin, base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parserOptions maps the JWT config onto the library's claims validator.
+// exp and nbf are always checked when validation is on; iat must not be in the future.
+func parserOptions(cfg *config.Config) []jwt.ParserOption {
+	if !cfg.JWTValidateExp {
+		return []jwt.ParserOption{jwt.WithoutClaimsValidation()}
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(cfg.JWTLeeway),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.JWTRequireExp {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+	return opts
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if strings.TrimSpace(s) == item {
+			return true
+		}
+	}
+	return false
+}
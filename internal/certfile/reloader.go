@@ -0,0 +1,81 @@
+// Package certfile serves a TLS key pair from files, re-reading it when the files are rotated.
+package certfile
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the key pair at CertFile/KeyFile, re-reading it when either file's modification time changes.
+// A failed reload keeps the previous pair, so a half-written rotation does not break handshakes.
+type Reloader struct {
+	certFile, keyFile string
+
+	mu              sync.Mutex
+	cert            *tls.Certificate
+	certMod, keyMod time.Time
+}
+
+// New loads the key pair once so configuration errors surface at startup.
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Certificate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Certificate returns the current key pair, reloading it if the files changed.
+func (r *Reloader) Certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	certInfo, certErr := os.Stat(r.certFile)
+	keyInfo, keyErr := os.Stat(r.keyFile)
+	if certErr != nil || keyErr != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("stat certificate: %w", errors.Join(certErr, keyErr))
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return r.cert, nil
+	}
+	pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	r.cert, r.certMod, r.keyMod = &pair, certInfo.ModTime(), keyInfo.ModTime()
+	return r.cert, nil
+}
+
+// GetCertificate is a tls.Config.GetCertificate callback for servers.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate()
+}
+
+// GetClientCertificate is a tls.Config.GetClientCertificate callback for clients.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate()
+}
+
+// Version parses "1.0".."1.3"; empty means TLS 1.2.
+func Version(v string) (uint16, error) {
+	switch v {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown tls version %q", v)
+}
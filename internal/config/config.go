@@ -1,7 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,21 +18,308 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getInt returns the integer in key, or defaultVal when unset. An unparsable value is appended to errs.
+func getInt(errs *[]error, key string, defaultVal int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %q is not an integer", key, v))
+		return defaultVal
+	}
+	return n
+}
+
+// getDuration returns the duration in key, or defaultVal when unset. An unparsable value is appended to errs.
+func getDuration(errs *[]error, key string, defaultVal time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %q is not a duration", key, v))
+		return defaultVal
+	}
+	return d
+}
+
 const (
 	DefaultHTTPPort    = "3000"
 	DefaultLokiTimeout = 15 * time.Second
+
+	DefaultACMEDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	DefaultACMECacheDir     = "acme-cache"
+
+	DefaultShutdownDelay       = 5 * time.Second
+	DefaultShutdownGracePeriod = 30 * time.Second
+
+	DefaultLokiMaxIdleConns        = 100
+	DefaultLokiMaxIdleConnsPerHost = 10
+	DefaultLokiIdleConnTimeout     = 90 * time.Second
+	DefaultLokiKeepAlive           = 30 * time.Second
+
+	DefaultRevocationReloadInterval = 30 * time.Second
+	DefaultTenantsReloadInterval    = 30 * time.Second
+	DefaultJWTLeeway                = 30 * time.Second
+
+	DefaultMaxBodyBytes         = 5 << 20
+	DefaultMaxDecompressedBytes = 20 << 20
+	DefaultMaxItemsPerType      = 1000
+	DefaultMaxStringLength      = 64 << 10
+	DefaultMaxMapEntries        = 200
+
+	DefaultMetricsPort       = "9090"
+	DefaultMetricsMaxTenants = 100
+	DefaultRUMMaxLabelValues = 100
+	DefaultRUMMaxSeries      = 10000
+
+	DefaultRemoteWriteInterval   = 30 * time.Second
+	DefaultRemoteWriteTimeout    = 15 * time.Second
+	DefaultRemoteWriteMaxRetries = 3
+
+	DefaultOTLPTimeout = 15 * time.Second
+
+	DefaultSinkQueueSize    = 10000
+	DefaultSinkQueueWorkers = 1
+	DefaultSinkTimeout      = 30 * time.Second
+
+	DefaultSinkBreakerFailures = 5
+	DefaultSinkBreakerCooldown = 30 * time.Second
+	DefaultLokiProbeInterval   = 15 * time.Second
+	DefaultReadyQueuePercent   = 90
+
+	DefaultTracingServiceName = "collector-fe-instrumentation"
+	DefaultTracingTimeout     = 10 * time.Second
+
+	// FileSinkStdout as FILE_SINK_PATH writes to standard output.
+	FileSinkStdout = "stdout"
+
+	DefaultFileSinkMaxBytes       = 100 << 20
+	DefaultFileSinkRotateInterval = 24 * time.Hour
+	DefaultFileSinkMaxFiles       = 7
+
+	DefaultElasticsearchIndex      = "faro-{tenant}-{date}"
+	DefaultElasticsearchTimeout    = 15 * time.Second
+	DefaultElasticsearchMaxRetries = 3
+
+	DefaultClickHouseDatabase = "faro"
+	DefaultClickHouseTimeout  = 15 * time.Second
+
+	DefaultWebhookFormat         = "json"
+	DefaultWebhookDedupWindow    = time.Hour
+	DefaultWebhookSpikeThreshold = 100
+	DefaultWebhookMaxAlerts      = 60
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookMaxRetries     = 3
+
+	DefaultKafkaTopic              = "faro-{kind}"
+	DefaultKafkaMaxBufferedRecords = 10000
+	DefaultKafkaDeliveryTimeout    = 30 * time.Second
+)
+
+// Sinks selectable with SINK.
+const (
+	SinkLoki          = "loki"
+	SinkOTLP          = "otlp"
+	SinkFile          = "file"
+	SinkElasticsearch = "elasticsearch"
+	SinkClickHouse    = "clickhouse"
+	SinkWebhook       = "webhook"
+	SinkKafka         = "kafka"
 )
 
 // Config holds application configuration from environment.
 type Config struct {
-	SecretKey      string
-	LokiURL        string
-	LokiToken      string
-	AllowOrigins   []string
-	HTTPPort       string
-	LokiTimeout    time.Duration
+	SecretKey    string
+	LokiURL      string
+	LokiToken    string
+	AllowOrigins []string
+	HTTPPort     string
+	LokiTimeout  time.Duration
+	// LokiCAFile, LokiCertFile and LokiKeyFile enable a private CA and mTLS towards Loki.
+	LokiCAFile        string
+	LokiCertFile      string
+	LokiKeyFile       string
+	LokiServerName    string
+	LokiTLSMinVersion string
+	// LokiProxyURL forces a proxy; empty honours HTTP(S)_PROXY.
+	LokiProxyURL            string
+	LokiMaxIdleConns        int
+	LokiMaxIdleConnsPerHost int
+	LokiIdleConnTimeout     time.Duration
+	LokiKeepAlive           time.Duration
+	// JWTValidateExp enables exp/nbf/iat validation (default true).
 	JWTValidateExp bool
 	JWTIssuer      string
+	// JWTLeeway is the clock skew tolerated on exp, nbf and iat.
+	JWTLeeway time.Duration
+	// JWTMaxTokenAge rejects tokens whose iat is older than this; 0 disables the check.
+	JWTMaxTokenAge time.Duration
+	// JWTRequireExp rejects tokens without an exp claim.
+	JWTRequireExp bool
+	// JWTBindClaims enforces the optional tenant and origins claims on /collect (JWT_ENFORCE_BINDING).
+	JWTBindClaims bool
+	// AllowPathToken keeps the legacy /collect/:tenant/:token route; header auth is always available.
+	AllowPathToken bool
+	// BeaconQueryToken accepts ?token= on /beacon routes, where sendBeacon cannot set headers.
+	BeaconQueryToken bool
+
+	// RevocationFile lists revoked tokens (jti:<id> or sha256:<hex> per line); empty disables revocation.
+	RevocationFile           string
+	RevocationReloadInterval time.Duration
+	// TenantsFile is the tenant registry (per-tenant Loki endpoints); when set, unknown tenants are rejected.
+	TenantsFile           string
+	TenantsReloadInterval time.Duration
+	// AdminToken protects the /admin endpoints; empty disables them.
+	AdminToken string
+
+	RateLimits RateLimitConfig
+	// TrustedProxies are the proxy IPs or CIDRs whose X-Forwarded-For is believed for the client IP;
+	// empty uses the peer address.
+	TrustedProxies []string
+
+	// MaxBodyBytes caps the request body (413 above it); the other limits bound the decoded payload.
+	MaxBodyBytes int64
+	// MaxDecompressedBytes caps a Content-Encoding body after decompression.
+	MaxDecompressedBytes int64
+	MaxItemsPerType      int
+	MaxStringLength      int
+	MaxMapEntries        int
+
+	// TLSCertFile and TLSKeyFile terminate TLS (with HTTP/2) on PORT; ACMEDomains obtains certificates instead.
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSMinVersion    string
+	ACMEDomains      []string
+	ACMEEmail        string
+	ACMEDirectoryURL string
+	ACMECAFile       string
+	ACMECacheDir     string
+	// HTTPRedirectPort serves plain HTTP redirecting to HTTPS (and ACME http-01 challenges); empty disables it.
+	HTTPRedirectPort string
+
+	// ShutdownDelay keeps serving with /readyz failing before the listener closes, so load balancers can react.
+	ShutdownDelay time.Duration
+	// ShutdownGracePeriod bounds draining in-flight requests and flushing the sinks after the delay.
+	ShutdownGracePeriod time.Duration
+
+	// MetricsEnabled exposes Prometheus metrics on /metrics on MetricsPort, a separate listener
+	// kept off the public port.
+	MetricsEnabled bool
+	MetricsPort    string
+	// MetricsMaxTenants bounds the tenant label when no tenant registry is configured.
+	MetricsMaxTenants int
+	// RUMMetricsEnabled derives Web Vitals histograms from measurements; RUMMaxLabelValues bounds their cardinality.
+	RUMMetricsEnabled bool
+	RUMMaxLabelValues int
+	// RUMMaxSeries caps the label sets across all RUM metrics; new ones beyond it are dropped.
+	RUMMaxSeries int
+
+	// RemoteWriteURL pushes the RUM metrics per tenant (X-Scope-OrgID) with Prometheus remote-write; empty disables it.
+	RemoteWriteURL        string
+	RemoteWriteToken      string
+	RemoteWriteInterval   time.Duration
+	RemoteWriteTimeout    time.Duration
+	RemoteWriteMaxRetries int
+
+	// Sinks lists where items are written: "loki" (default) and/or "otlp". With more than one sink
+	// or with SinkRoutes, items are fanned out through per-sink queues.
+	Sinks      []string
+	SinkRoutes []SinkRoute
+	// SinkQueueSize bounds the items waiting per sink; SinkQueueWorkers are the concurrent writers per sink.
+	SinkQueueSize    int
+	SinkQueueWorkers int
+	SinkTimeout      time.Duration
+	// SinkBreakerFailures consecutive write errors open a sink's circuit for SinkBreakerCooldown; 0 disables it.
+	SinkBreakerFailures int
+	SinkBreakerCooldown time.Duration
+	// LokiProbeInterval is how often Loki's /ready is polled for /readyz; 0 disables the probe.
+	LokiProbeInterval time.Duration
+	// ReadyQueuePercent fails /readyz while a sink queue is at least this full.
+	ReadyQueuePercent int
+
+	// TracingEndpoint enables tracing of the collector itself, exported over OTLP/HTTP
+	// (/v1/traces is appended when it has no path).
+	TracingEndpoint string
+	TracingHeaders  map[string]string
+	// TracingSampleRatio is the share of traces recorded; the sampled flag of an incoming traceparent is ignored.
+	TracingSampleRatio float64
+	TracingServiceName string
+	TracingTimeout     time.Duration
+
+	// OTLPEndpoint is the OTLP/HTTP base URL (/v1/logs is appended when it has no path).
+	OTLPEndpoint string
+	// OTLPProtocol is "http/protobuf" (default) or "http/json".
+	OTLPProtocol string
+	OTLPHeaders  map[string]string
+	OTLPTimeout  time.Duration
+
+	// FileSinkPath is where the file sink writes NDJSON; "stdout" writes to standard output without rotation,
+	// interleaved with the collector's own logs. It has no default.
+	FileSinkPath           string
+	FileSinkMaxBytes       int64
+	FileSinkRotateInterval time.Duration
+	FileSinkMaxFiles       int
+	FileSinkCompress       bool
+
+	// ElasticsearchURL is the Elasticsearch/OpenSearch base URL for the bulk sink.
+	ElasticsearchURL      string
+	ElasticsearchUsername string
+	ElasticsearchPassword string
+	ElasticsearchAPIKey   string
+	// ElasticsearchIndex may use {tenant}, {app}, {env}, {kind} and {date}; {app} and {env} need
+	// ElasticsearchIndexApps and ElasticsearchIndexEnvs.
+	ElasticsearchIndex string
+	// ElasticsearchIndexApps and ElasticsearchIndexEnvs list the values {app} and {env} may expand to;
+	// other values index into "other".
+	ElasticsearchIndexApps  []string
+	ElasticsearchIndexEnvs  []string
+	ElasticsearchTimeout    time.Duration
+	ElasticsearchMaxRetries int
+
+	// ClickHouseURL is the ClickHouse HTTP interface (e.g. http://clickhouse:8123).
+	ClickHouseURL      string
+	ClickHouseDatabase string
+	ClickHouseUsername string
+	ClickHousePassword string
+	ClickHouseTimeout  time.Duration
+	// ClickHouseCreateSchema applies the bundled DDL at startup.
+	ClickHouseCreateSchema bool
+
+	// WebhookURL receives alerts for new or spiking exception fingerprints.
+	WebhookURL string
+	// WebhookFormat is "json" or "slack"; WebhookTemplateFile overrides it with a text/template body.
+	WebhookFormat       string
+	WebhookTemplateFile string
+	// WebhookSecret signs requests with HMAC-SHA256 (X-Faro-Signature-256).
+	WebhookSecret         string
+	WebhookDedupWindow    time.Duration
+	WebhookSpikeThreshold int
+	// WebhookMaxAlerts caps the alerts sent per minute across all fingerprints; 0 disables the cap.
+	WebhookMaxAlerts  int
+	WebhookTimeout    time.Duration
+	WebhookMaxRetries int
+
+	// KafkaBrokers are the seed brokers of the Kafka sink.
+	KafkaBrokers []string
+	// KafkaTopic may use {tenant}, {kind} and {app}; {app} needs KafkaTopicApps.
+	KafkaTopic string
+	// KafkaTopicApps lists the app names {app} may expand to; other apps publish to "other".
+	KafkaTopicApps []string
+	// KafkaAutoCreateTopics lets the brokers create missing topics.
+	KafkaAutoCreateTopics bool
+	// KafkaBatch publishes one record per topic and session instead of one per item.
+	KafkaBatch              bool
+	KafkaCompression        string
+	KafkaAcks               string
+	KafkaMaxBufferedRecords int
+	KafkaDeliveryTimeout    time.Duration
+
+	// loadErr collects values that could not be parsed; reported by Validate.
+	loadErr error
 }
 
 // Load reads config from environment.
@@ -39,35 +331,424 @@ func Load() *Config {
 			list = append(list, t)
 		}
 	}
-	validateExp := strings.ToLower(getEnv("JWT_VALIDATE_EXP", "false")) == "true"
-	return &Config{
-		SecretKey:      getEnv("SECRET_KEY", ""),
-		LokiURL:        getEnv("LOKI_URL", ""),
-		LokiToken:      getEnv("LOKI_API_TOKEN", ""),
-		AllowOrigins:   list,
-		HTTPPort:       getEnv("PORT", DefaultHTTPPort),
-		LokiTimeout:    DefaultLokiTimeout,
+	validateExp := strings.ToLower(getEnv("JWT_VALIDATE_EXP", "true")) == "true"
+	requireExp := strings.ToLower(getEnv("JWT_REQUIRE_EXP", "false")) == "true"
+	bindClaims := strings.ToLower(getEnv("JWT_ENFORCE_BINDING", "false")) == "true"
+	allowPathToken := strings.ToLower(getEnv("ALLOW_PATH_TOKEN", "true")) == "true"
+	beaconQueryToken := strings.ToLower(getEnv("ALLOW_BEACON_QUERY_TOKEN", "true")) == "true"
+	var errs []error
+	rateLimits := loadRateLimits(&errs)
+	sinkRoutes, err := ParseSinkRoutes(getEnv("SINK_ROUTES", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("SINK_ROUTES: %w", err))
+	}
+	otlpHeaders, err := parseHeaders(getEnv("OTLP_HEADERS", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("OTLP_HEADERS: %w", err))
+	}
+	tracingHeaders, err := parseHeaders(getEnv("TRACING_OTLP_HEADERS", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("TRACING_OTLP_HEADERS: %w", err))
+	}
+	tracingSampleRatio, err := strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO: %w", err))
+	}
+	c := &Config{
+		SecretKey:    getEnv("SECRET_KEY", ""),
+		LokiURL:      getEnv("LOKI_URL", ""),
+		LokiToken:    getEnv("LOKI_API_TOKEN", ""),
+		AllowOrigins: list,
+		HTTPPort:     getEnv("PORT", DefaultHTTPPort),
+		LokiTimeout:  DefaultLokiTimeout,
+
+		LokiCAFile:              getEnv("LOKI_CA_FILE", ""),
+		LokiCertFile:            getEnv("LOKI_CERT_FILE", ""),
+		LokiKeyFile:             getEnv("LOKI_KEY_FILE", ""),
+		LokiServerName:          getEnv("LOKI_SERVER_NAME", ""),
+		LokiTLSMinVersion:       getEnv("LOKI_TLS_MIN_VERSION", "1.2"),
+		LokiProxyURL:            getEnv("LOKI_PROXY_URL", ""),
+		LokiMaxIdleConns:        getInt(&errs, "LOKI_MAX_IDLE_CONNS", DefaultLokiMaxIdleConns),
+		LokiMaxIdleConnsPerHost: getInt(&errs, "LOKI_MAX_IDLE_CONNS_PER_HOST", DefaultLokiMaxIdleConnsPerHost),
+		LokiIdleConnTimeout:     getDuration(&errs, "LOKI_IDLE_CONN_TIMEOUT", DefaultLokiIdleConnTimeout),
+		LokiKeepAlive:           getDuration(&errs, "LOKI_KEEP_ALIVE", DefaultLokiKeepAlive),
+
 		JWTValidateExp: validateExp,
 		JWTIssuer:      getEnv("JWT_ISSUER", "trusted-issuer"),
+		JWTLeeway:      getDuration(&errs, "JWT_LEEWAY", DefaultJWTLeeway),
+		JWTMaxTokenAge: getDuration(&errs, "JWT_MAX_TOKEN_AGE", 0),
+		JWTRequireExp:  requireExp,
+		JWTBindClaims:  bindClaims,
+		AllowPathToken: allowPathToken,
+
+		BeaconQueryToken: beaconQueryToken,
+
+		RevocationFile:           getEnv("REVOCATION_FILE", ""),
+		RevocationReloadInterval: getDuration(&errs, "REVOCATION_RELOAD_INTERVAL", DefaultRevocationReloadInterval),
+		AdminToken:               getEnv("ADMIN_TOKEN", ""),
+
+		TenantsFile:           getEnv("TENANTS_FILE", ""),
+		TenantsReloadInterval: getDuration(&errs, "TENANTS_RELOAD_INTERVAL", DefaultTenantsReloadInterval),
+
+		RateLimits:     rateLimits,
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
+
+		MaxBodyBytes:         int64(getInt(&errs, "MAX_BODY_BYTES", DefaultMaxBodyBytes)),
+		MaxDecompressedBytes: int64(getInt(&errs, "MAX_DECOMPRESSED_BYTES", DefaultMaxDecompressedBytes)),
+		MaxItemsPerType:      getInt(&errs, "MAX_ITEMS_PER_TYPE", DefaultMaxItemsPerType),
+		MaxStringLength:      getInt(&errs, "MAX_STRING_LENGTH", DefaultMaxStringLength),
+		MaxMapEntries:        getInt(&errs, "MAX_MAP_ENTRIES", DefaultMaxMapEntries),
+
+		TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+		TLSMinVersion:    getEnv("TLS_MIN_VERSION", "1.2"),
+		ACMEDomains:      splitList(getEnv("ACME_DOMAINS", "")),
+		ACMEEmail:        getEnv("ACME_EMAIL", ""),
+		ACMEDirectoryURL: getEnv("ACME_DIRECTORY_URL", DefaultACMEDirectoryURL),
+		ACMECAFile:       getEnv("ACME_CA_FILE", ""),
+		ACMECacheDir:     getEnv("ACME_CACHE_DIR", DefaultACMECacheDir),
+		HTTPRedirectPort: getEnv("HTTP_REDIRECT_PORT", ""),
+
+		ShutdownDelay:       getDuration(&errs, "SHUTDOWN_DELAY", DefaultShutdownDelay),
+		ShutdownGracePeriod: getDuration(&errs, "SHUTDOWN_GRACE_PERIOD", DefaultShutdownGracePeriod),
+
+		MetricsEnabled:    strings.ToLower(getEnv("METRICS_ENABLED", "true")) == "true",
+		MetricsPort:       getEnv("METRICS_PORT", DefaultMetricsPort),
+		MetricsMaxTenants: getInt(&errs, "METRICS_MAX_TENANTS", DefaultMetricsMaxTenants),
+		RUMMetricsEnabled: strings.ToLower(getEnv("RUM_METRICS_ENABLED", "true")) == "true",
+		RUMMaxLabelValues: getInt(&errs, "RUM_MAX_LABEL_VALUES", DefaultRUMMaxLabelValues),
+		RUMMaxSeries:      getInt(&errs, "RUM_MAX_SERIES", DefaultRUMMaxSeries),
+
+		RemoteWriteURL:        getEnv("REMOTE_WRITE_URL", ""),
+		RemoteWriteToken:      getEnv("REMOTE_WRITE_TOKEN", ""),
+		RemoteWriteInterval:   getDuration(&errs, "REMOTE_WRITE_INTERVAL", DefaultRemoteWriteInterval),
+		RemoteWriteTimeout:    getDuration(&errs, "REMOTE_WRITE_TIMEOUT", DefaultRemoteWriteTimeout),
+		RemoteWriteMaxRetries: getInt(&errs, "REMOTE_WRITE_MAX_RETRIES", DefaultRemoteWriteMaxRetries),
+
+		Sinks:            splitList(strings.ToLower(getEnv("SINK", SinkLoki))),
+		SinkRoutes:       sinkRoutes,
+		SinkQueueSize:    getInt(&errs, "SINK_QUEUE_SIZE", DefaultSinkQueueSize),
+		SinkQueueWorkers: getInt(&errs, "SINK_QUEUE_WORKERS", DefaultSinkQueueWorkers),
+		SinkTimeout:      getDuration(&errs, "SINK_TIMEOUT", DefaultSinkTimeout),
+
+		SinkBreakerFailures: getInt(&errs, "SINK_BREAKER_FAILURES", DefaultSinkBreakerFailures),
+		SinkBreakerCooldown: getDuration(&errs, "SINK_BREAKER_COOLDOWN", DefaultSinkBreakerCooldown),
+		LokiProbeInterval:   getDuration(&errs, "LOKI_PROBE_INTERVAL", DefaultLokiProbeInterval),
+		ReadyQueuePercent:   getInt(&errs, "READY_QUEUE_PERCENT", DefaultReadyQueuePercent),
+
+		TracingEndpoint:    getEnv("TRACING_OTLP_ENDPOINT", ""),
+		TracingHeaders:     tracingHeaders,
+		TracingSampleRatio: tracingSampleRatio,
+		TracingServiceName: getEnv("TRACING_SERVICE_NAME", DefaultTracingServiceName),
+		TracingTimeout:     getDuration(&errs, "TRACING_TIMEOUT", DefaultTracingTimeout),
+
+		OTLPEndpoint: getEnv("OTLP_ENDPOINT", ""),
+		OTLPProtocol: getEnv("OTLP_PROTOCOL", "http/protobuf"),
+		OTLPHeaders:  otlpHeaders,
+		OTLPTimeout:  getDuration(&errs, "OTLP_TIMEOUT", DefaultOTLPTimeout),
+
+		FileSinkPath:           getEnv("FILE_SINK_PATH", ""),
+		FileSinkMaxBytes:       int64(getInt(&errs, "FILE_SINK_MAX_BYTES", DefaultFileSinkMaxBytes)),
+		FileSinkRotateInterval: getDuration(&errs, "FILE_SINK_ROTATE_INTERVAL", DefaultFileSinkRotateInterval),
+		FileSinkMaxFiles:       getInt(&errs, "FILE_SINK_MAX_FILES", DefaultFileSinkMaxFiles),
+		FileSinkCompress:       strings.ToLower(getEnv("FILE_SINK_COMPRESS", "false")) == "true",
+
+		ElasticsearchURL:        getEnv("ELASTICSEARCH_URL", ""),
+		ElasticsearchUsername:   getEnv("ELASTICSEARCH_USERNAME", ""),
+		ElasticsearchPassword:   getEnv("ELASTICSEARCH_PASSWORD", ""),
+		ElasticsearchAPIKey:     getEnv("ELASTICSEARCH_API_KEY", ""),
+		ElasticsearchIndex:      getEnv("ELASTICSEARCH_INDEX", DefaultElasticsearchIndex),
+		ElasticsearchIndexApps:  splitList(getEnv("ELASTICSEARCH_INDEX_APPS", "")),
+		ElasticsearchIndexEnvs:  splitList(getEnv("ELASTICSEARCH_INDEX_ENVS", "")),
+		ElasticsearchTimeout:    getDuration(&errs, "ELASTICSEARCH_TIMEOUT", DefaultElasticsearchTimeout),
+		ElasticsearchMaxRetries: getInt(&errs, "ELASTICSEARCH_MAX_RETRIES", DefaultElasticsearchMaxRetries),
+
+		ClickHouseURL:          getEnv("CLICKHOUSE_URL", ""),
+		ClickHouseDatabase:     getEnv("CLICKHOUSE_DATABASE", DefaultClickHouseDatabase),
+		ClickHouseUsername:     getEnv("CLICKHOUSE_USERNAME", ""),
+		ClickHousePassword:     getEnv("CLICKHOUSE_PASSWORD", ""),
+		ClickHouseTimeout:      getDuration(&errs, "CLICKHOUSE_TIMEOUT", DefaultClickHouseTimeout),
+		ClickHouseCreateSchema: strings.ToLower(getEnv("CLICKHOUSE_CREATE_SCHEMA", "false")) == "true",
+
+		WebhookURL:            getEnv("WEBHOOK_URL", ""),
+		WebhookFormat:         strings.ToLower(getEnv("WEBHOOK_FORMAT", DefaultWebhookFormat)),
+		WebhookTemplateFile:   getEnv("WEBHOOK_TEMPLATE_FILE", ""),
+		WebhookSecret:         getEnv("WEBHOOK_SECRET", ""),
+		WebhookDedupWindow:    getDuration(&errs, "WEBHOOK_DEDUP_WINDOW", DefaultWebhookDedupWindow),
+		WebhookSpikeThreshold: getInt(&errs, "WEBHOOK_SPIKE_THRESHOLD", DefaultWebhookSpikeThreshold),
+		WebhookMaxAlerts:      getInt(&errs, "WEBHOOK_MAX_ALERTS_PER_MINUTE", DefaultWebhookMaxAlerts),
+		WebhookTimeout:        getDuration(&errs, "WEBHOOK_TIMEOUT", DefaultWebhookTimeout),
+		WebhookMaxRetries:     getInt(&errs, "WEBHOOK_MAX_RETRIES", DefaultWebhookMaxRetries),
+
+		KafkaBrokers:            splitList(getEnv("KAFKA_BROKERS", "")),
+		KafkaTopic:              getEnv("KAFKA_TOPIC", DefaultKafkaTopic),
+		KafkaTopicApps:          splitList(getEnv("KAFKA_TOPIC_APPS", "")),
+		KafkaAutoCreateTopics:   strings.ToLower(getEnv("KAFKA_AUTO_CREATE_TOPICS", "false")) == "true",
+		KafkaBatch:              strings.ToLower(getEnv("KAFKA_BATCH", "false")) == "true",
+		KafkaCompression:        strings.ToLower(getEnv("KAFKA_COMPRESSION", "none")),
+		KafkaAcks:               strings.ToLower(getEnv("KAFKA_ACKS", "all")),
+		KafkaMaxBufferedRecords: getInt(&errs, "KAFKA_MAX_BUFFERED_RECORDS", DefaultKafkaMaxBufferedRecords),
+		KafkaDeliveryTimeout:    getDuration(&errs, "KAFKA_DELIVERY_TIMEOUT", DefaultKafkaDeliveryTimeout),
 	}
+	c.loadErr = errors.Join(errs...)
+	return c
 }
 
 // Validate returns an error if required fields are missing.
 func (c *Config) Validate() error {
+	if c.loadErr != nil {
+		return c.loadErr
+	}
 	if c.SecretKey == "" {
 		return ErrMissingSecretKey
 	}
 	if len(c.SecretKey) < 64 {
 		return ErrSecretKeyTooShort
 	}
-	if c.LokiURL == "" {
-		return ErrMissingLokiURL
+	for _, p := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return ErrInvalidTrustedProxies
+		}
+	}
+	if c.RevocationFile != "" && c.RevocationReloadInterval <= 0 {
+		return ErrInvalidRevocationReload
 	}
-	if c.LokiToken == "" {
-		return ErrMissingLokiToken
+	if c.TenantsFile != "" && c.TenantsReloadInterval <= 0 {
+		return ErrInvalidTenantsReload
+	}
+	if len(c.Sinks) == 0 {
+		return ErrUnknownSink
+	}
+	for _, sink := range c.Sinks {
+		switch sink {
+		case SinkLoki:
+			if (c.LokiCertFile == "") != (c.LokiKeyFile == "") {
+				return ErrLokiClientCert
+			}
+			if !oneOf(c.LokiTLSMinVersion, "1.0", "1.1", "1.2", "1.3") || c.LokiMaxIdleConns <= 0 ||
+				c.LokiMaxIdleConnsPerHost <= 0 || c.LokiIdleConnTimeout <= 0 || c.LokiKeepAlive < 0 {
+				return ErrInvalidLokiTransport
+			}
+			if c.LokiProxyURL != "" {
+				if u, err := url.Parse(c.LokiProxyURL); err != nil || u.Host == "" {
+					return ErrInvalidLokiTransport
+				}
+			}
+			if c.TenantsFile != "" {
+				// Endpoints come from the registry; LOKI_URL/LOKI_API_TOKEN are only defaults.
+				continue
+			}
+			if c.LokiURL == "" {
+				return ErrMissingLokiURL
+			}
+			if c.LokiToken == "" {
+				return ErrMissingLokiToken
+			}
+		case SinkOTLP:
+			if c.OTLPEndpoint == "" {
+				return ErrMissingOTLPEndpoint
+			}
+			if c.OTLPProtocol != "http/protobuf" && c.OTLPProtocol != "http/json" {
+				return ErrInvalidOTLPProtocol
+			}
+		case SinkFile:
+			if c.FileSinkPath == "" {
+				return ErrMissingFileSinkPath
+			}
+			if c.FileSinkMaxBytes < 0 || c.FileSinkRotateInterval < 0 || c.FileSinkMaxFiles < 0 {
+				return ErrInvalidFileSink
+			}
+		case SinkElasticsearch:
+			if c.ElasticsearchURL == "" {
+				return ErrMissingElasticsearchURL
+			}
+			if c.ElasticsearchAPIKey != "" && c.ElasticsearchUsername != "" {
+				return ErrElasticsearchAuthConflict
+			}
+			if c.ElasticsearchMaxRetries < 0 || !validIndexPattern(c.ElasticsearchIndex) {
+				return ErrInvalidElasticsearch
+			}
+			if strings.Contains(c.ElasticsearchIndex, "{app}") && len(c.ElasticsearchIndexApps) == 0 ||
+				strings.Contains(c.ElasticsearchIndex, "{env}") && len(c.ElasticsearchIndexEnvs) == 0 {
+				return ErrElasticsearchAllowlist
+			}
+		case SinkClickHouse:
+			if c.ClickHouseURL == "" {
+				return ErrMissingClickHouseURL
+			}
+			if !validIdentifier(c.ClickHouseDatabase) {
+				return ErrInvalidClickHouseDatabase
+			}
+		case SinkWebhook:
+			if c.WebhookURL == "" {
+				return ErrMissingWebhookURL
+			}
+			if c.WebhookFormat != "json" && c.WebhookFormat != "slack" || c.WebhookDedupWindow <= 0 ||
+				c.WebhookSpikeThreshold < 0 || c.WebhookMaxAlerts < 0 || c.WebhookMaxRetries < 0 {
+				return ErrInvalidWebhook
+			}
+		case SinkKafka:
+			if len(c.KafkaBrokers) == 0 {
+				return ErrMissingKafkaBrokers
+			}
+			if !oneOf(c.KafkaCompression, "none", "gzip", "snappy", "lz4", "zstd") || !oneOf(c.KafkaAcks, "all", "leader", "none") ||
+				c.KafkaMaxBufferedRecords <= 0 || c.KafkaDeliveryTimeout <= 0 {
+				return ErrInvalidKafka
+			}
+			if strings.Contains(c.KafkaTopic, "{app}") && len(c.KafkaTopicApps) == 0 {
+				return ErrKafkaTopicApps
+			}
+		default:
+			return ErrUnknownSink
+		}
+	}
+	for _, r := range c.SinkRoutes {
+		for _, sink := range r.Sinks {
+			if !c.HasSink(sink) {
+				return fmt.Errorf("%w: %s", ErrRouteUnknownSink, sink)
+			}
+		}
+	}
+	if c.SinkQueueSize <= 0 || c.SinkQueueWorkers <= 0 || c.SinkTimeout <= 0 {
+		return ErrInvalidSinkQueue
+	}
+	if c.SinkBreakerFailures < 0 || c.SinkBreakerCooldown <= 0 || c.LokiProbeInterval < 0 ||
+		c.ReadyQueuePercent <= 0 || c.ReadyQueuePercent > 100 {
+		return ErrInvalidReadiness
+	}
+	if c.TracingEndpoint != "" {
+		// The ratio check is negated so that NaN, which fails every comparison, is rejected.
+		if u, err := url.Parse(c.TracingEndpoint); err != nil || u.Host == "" ||
+			!(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1) || c.TracingTimeout <= 0 {
+			return ErrInvalidTracing
+		}
 	}
 	if len(c.AllowOrigins) == 0 {
 		return ErrMissingAllowOrigins
 	}
+	if c.JWTLeeway < 0 || c.JWTMaxTokenAge < 0 {
+		return ErrNegativeJWTDuration
+	}
+	if !c.JWTValidateExp && (c.JWTRequireExp || c.JWTMaxTokenAge > 0) {
+		return ErrJWTTimeChecksDisabled
+	}
+	if c.MaxBodyBytes <= 0 || c.MaxDecompressedBytes <= 0 || c.MaxItemsPerType < 0 || c.MaxStringLength < 0 || c.MaxMapEntries < 0 {
+		return ErrInvalidPayloadLimits
+	}
+	if c.MetricsEnabled && (c.MetricsPort == "" || c.MetricsPort == c.HTTPPort || c.MetricsPort == c.HTTPRedirectPort) {
+		return ErrMetricsPortConflict
+	}
+	if c.MetricsMaxTenants <= 0 {
+		return ErrInvalidMetricsTenants
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") || (c.TLSCertFile != "" && len(c.ACMEDomains) > 0) {
+		return ErrInvalidServerTLS
+	}
+	if !oneOf(c.TLSMinVersion, "1.0", "1.1", "1.2", "1.3") {
+		return ErrInvalidServerTLS
+	}
+	if c.ShutdownDelay < 0 || c.ShutdownGracePeriod <= 0 {
+		return ErrInvalidShutdown
+	}
+	if c.HTTPRedirectPort != "" && (!c.TLSEnabled() || c.HTTPRedirectPort == c.HTTPPort) {
+		return ErrInvalidRedirectPort
+	}
+	if c.RemoteWriteURL != "" && (!c.RUMMetricsEnabled || c.RemoteWriteInterval <= 0 || c.RemoteWriteMaxRetries < 0) {
+		return ErrInvalidRemoteWrite
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < 32 {
+		return ErrAdminTokenTooShort
+	}
 	return nil
 }
+
+// HasSink reports whether name is one of the configured sinks.
+func (c *Config) HasSink(name string) bool {
+	for _, s := range c.Sinks {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Fanout reports whether items go through the sink router rather than straight to a single sink.
+func (c *Config) Fanout() bool {
+	return len(c.Sinks) > 1 || len(c.SinkRoutes) > 0
+}
+
+// validIndexPattern accepts lowercase index names whose placeholders are all known.
+func validIndexPattern(p string) bool {
+	rest := strings.NewReplacer("{tenant}", "", "{app}", "", "{env}", "", "{kind}", "", "{date}", "").Replace(p)
+	return p != "" && !strings.ContainsAny(rest, "{}") && rest == strings.ToLower(rest)
+}
+
+// validIdentifier accepts unquoted SQL identifiers.
+func validIdentifier(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// TLSEnabled reports whether the collector terminates TLS itself.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.ACMEDomains) > 0
+}
+
+// Warnings lists settings that are valid but likely to fail at runtime, for logging at startup.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	// Let's Encrypt validates on the standard ports: tls-alpn-01 on 443 (PORT) and http-01 on 80 (HTTP_REDIRECT_PORT).
+	if len(c.ACMEDomains) > 0 && c.HTTPPort != "443" && c.HTTPRedirectPort != "80" {
+		warnings = append(warnings, "ACME_DOMAINS is set but PORT is not 443 and HTTP_REDIRECT_PORT is not 80; "+
+			"certificates are only issued if public port 443 or 80 is forwarded to them")
+	}
+	return warnings
+}
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(v); t != "" {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// parseHeaders parses "k1=v1,k2=v2" (OTEL_EXPORTER_OTLP_HEADERS format; values may be URL-encoded).
+func parseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid header %q: expected key=value", pair)
+		}
+		v = strings.TrimSpace(v)
+		if dec, err := url.QueryUnescape(v); err == nil {
+			v = dec
+		}
+		headers[k] = v
+	}
+	return headers, nil
+}
@@ -3,9 +3,44 @@ package config
 import "errors"
 
 var (
-	ErrMissingSecretKey     = errors.New("missing required env: SECRET_KEY")
-	ErrSecretKeyTooShort    = errors.New("SECRET_KEY must be at least 64 characters")
-	ErrMissingLokiURL       = errors.New("missing required env: LOKI_URL")
-	ErrMissingLokiToken     = errors.New("missing required env: LOKI_API_TOKEN")
-	ErrMissingAllowOrigins  = errors.New("missing required env: ALLOW_ORIGINS")
+	ErrMissingSecretKey          = errors.New("missing required env: SECRET_KEY")
+	ErrSecretKeyTooShort         = errors.New("SECRET_KEY must be at least 64 characters")
+	ErrMissingLokiURL            = errors.New("missing required env: LOKI_URL")
+	ErrMissingLokiToken          = errors.New("missing required env: LOKI_API_TOKEN")
+	ErrLokiClientCert            = errors.New("LOKI_CERT_FILE and LOKI_KEY_FILE must be set together")
+	ErrInvalidLokiTransport      = errors.New("LOKI_TLS_MIN_VERSION must be 1.0-1.3, LOKI_PROXY_URL a valid URL, LOKI_MAX_IDLE_CONNS, LOKI_MAX_IDLE_CONNS_PER_HOST, LOKI_IDLE_CONN_TIMEOUT positive and LOKI_KEEP_ALIVE >= 0")
+	ErrMissingAllowOrigins       = errors.New("missing required env: ALLOW_ORIGINS")
+	ErrNegativeJWTDuration       = errors.New("JWT_LEEWAY and JWT_MAX_TOKEN_AGE must not be negative")
+	ErrJWTTimeChecksDisabled     = errors.New("JWT_REQUIRE_EXP and JWT_MAX_TOKEN_AGE need JWT_VALIDATE_EXP=true")
+	ErrInvalidPayloadLimits      = errors.New("MAX_BODY_BYTES and MAX_DECOMPRESSED_BYTES must be positive and MAX_ITEMS_PER_TYPE, MAX_STRING_LENGTH, MAX_MAP_ENTRIES not negative")
+	ErrMetricsPortConflict       = errors.New("METRICS_PORT must be set and differ from PORT and HTTP_REDIRECT_PORT")
+	ErrInvalidMetricsTenants     = errors.New("METRICS_MAX_TENANTS must be positive")
+	ErrInvalidServerTLS          = errors.New("set TLS_CERT_FILE and TLS_KEY_FILE together or ACME_DOMAINS, not both, and TLS_MIN_VERSION to 1.0-1.3")
+	ErrInvalidShutdown           = errors.New("SHUTDOWN_DELAY must not be negative and SHUTDOWN_GRACE_PERIOD must be positive")
+	ErrInvalidRedirectPort       = errors.New("HTTP_REDIRECT_PORT needs TLS_CERT_FILE or ACME_DOMAINS and must differ from PORT")
+	ErrInvalidRemoteWrite        = errors.New("REMOTE_WRITE_URL needs RUM_METRICS_ENABLED=true, a positive REMOTE_WRITE_INTERVAL and REMOTE_WRITE_MAX_RETRIES >= 0")
+	ErrAdminTokenTooShort        = errors.New("ADMIN_TOKEN must be at least 32 characters")
+	ErrInvalidTrustedProxies     = errors.New("TRUSTED_PROXIES must list IP addresses or CIDRs")
+	ErrInvalidRevocationReload   = errors.New("REVOCATION_RELOAD_INTERVAL must be positive")
+	ErrInvalidTenantsReload      = errors.New("TENANTS_RELOAD_INTERVAL must be positive")
+	ErrUnknownSink               = errors.New("SINK must list one or more of: loki, otlp, file, elasticsearch, clickhouse, webhook, kafka")
+	ErrRouteUnknownSink          = errors.New("SINK_ROUTES references a sink not listed in SINK")
+	ErrMissingFileSinkPath       = errors.New("FILE_SINK_PATH is required for the file sink (a file path, or stdout)")
+	ErrInvalidFileSink           = errors.New("FILE_SINK_MAX_BYTES, FILE_SINK_ROTATE_INTERVAL and FILE_SINK_MAX_FILES must not be negative")
+	ErrMissingElasticsearchURL   = errors.New("missing required env for SINK=elasticsearch: ELASTICSEARCH_URL")
+	ErrElasticsearchAuthConflict = errors.New("set either ELASTICSEARCH_API_KEY or ELASTICSEARCH_USERNAME, not both")
+	ErrInvalidElasticsearch      = errors.New("ELASTICSEARCH_INDEX must be lowercase with only {tenant}, {app}, {env}, {kind}, {date} placeholders and ELASTICSEARCH_MAX_RETRIES >= 0")
+	ErrElasticsearchAllowlist    = errors.New("ELASTICSEARCH_INDEX with {app} needs ELASTICSEARCH_INDEX_APPS and with {env} ELASTICSEARCH_INDEX_ENVS")
+	ErrMissingClickHouseURL      = errors.New("missing required env for SINK=clickhouse: CLICKHOUSE_URL")
+	ErrInvalidClickHouseDatabase = errors.New("CLICKHOUSE_DATABASE must be a plain identifier (letters, digits, _)")
+	ErrMissingWebhookURL         = errors.New("missing required env for SINK=webhook: WEBHOOK_URL")
+	ErrInvalidWebhook            = errors.New("WEBHOOK_FORMAT must be json or slack, WEBHOOK_DEDUP_WINDOW positive and WEBHOOK_SPIKE_THRESHOLD, WEBHOOK_MAX_ALERTS_PER_MINUTE, WEBHOOK_MAX_RETRIES >= 0")
+	ErrMissingKafkaBrokers       = errors.New("missing required env for SINK=kafka: KAFKA_BROKERS")
+	ErrInvalidKafka              = errors.New("KAFKA_COMPRESSION must be none, gzip, snappy, lz4 or zstd, KAFKA_ACKS all, leader or none, and KAFKA_MAX_BUFFERED_RECORDS, KAFKA_DELIVERY_TIMEOUT positive")
+	ErrKafkaTopicApps            = errors.New("KAFKA_TOPIC with {app} needs KAFKA_TOPIC_APPS")
+	ErrInvalidTracing            = errors.New("TRACING_OTLP_ENDPOINT must be a URL, TRACING_SAMPLE_RATIO between 0 and 1 and TRACING_TIMEOUT positive")
+	ErrInvalidReadiness          = errors.New("SINK_BREAKER_FAILURES and LOKI_PROBE_INTERVAL must not be negative, SINK_BREAKER_COOLDOWN positive and READY_QUEUE_PERCENT 1-100")
+	ErrInvalidSinkQueue          = errors.New("SINK_QUEUE_SIZE, SINK_QUEUE_WORKERS and SINK_TIMEOUT must be positive")
+	ErrMissingOTLPEndpoint       = errors.New("missing required env for SINK=otlp: OTLP_ENDPOINT")
+	ErrInvalidOTLPProtocol       = errors.New("OTLP_PROTOCOL must be http/protobuf or http/json")
 )
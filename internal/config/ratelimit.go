@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RateLimit is a token-bucket limit for one scope (tenant, token or client IP).
+// Zero rates are unlimited.
+type RateLimit struct {
+	RequestsPerSec float64
+	ItemsPerSec    float64
+	BytesPerSec    float64
+	// BurstSeconds sizes each bucket as this many seconds of its rate (default 1).
+	BurstSeconds float64
+	// DailyItems caps items per UTC day; 0 disables the quota.
+	DailyItems int64
+}
+
+// Enabled reports whether any limit is set.
+func (l RateLimit) Enabled() bool {
+	return l.RequestsPerSec > 0 || l.ItemsPerSec > 0 || l.BytesPerSec > 0 || l.DailyItems > 0
+}
+
+// RateLimitConfig holds the limits per scope and the per-tenant overrides.
+type RateLimitConfig struct {
+	Tenant          RateLimit
+	Token           RateLimit
+	IP              RateLimit
+	TenantOverrides map[string]RateLimit
+}
+
+// Enabled reports whether any scope is limited.
+func (c RateLimitConfig) Enabled() bool {
+	if c.Tenant.Enabled() || c.Token.Enabled() || c.IP.Enabled() {
+		return true
+	}
+	for _, l := range c.TenantOverrides {
+		if l.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// ForTenant returns the tenant-scope limit, applying the override when present.
+func (c RateLimitConfig) ForTenant(tenant string) RateLimit {
+	if l, ok := c.TenantOverrides[tenant]; ok {
+		return l
+	}
+	return c.Tenant
+}
+
+// ParseRateLimit parses "rps=50,items=2000,bytes=1048576,burst=2,daily_items=1000000".
+// Omitted keys are unlimited.
+func ParseRateLimit(spec string) (RateLimit, error) {
+	l := RateLimit{BurstSeconds: 1}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return l, fmt.Errorf("rate limit %q: expected key=value", part)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "daily_items" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return l, fmt.Errorf("rate limit %q: invalid value", part)
+			}
+			l.DailyItems = n
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			return l, fmt.Errorf("rate limit %q: invalid value", part)
+		}
+		switch k {
+		case "rps":
+			l.RequestsPerSec = f
+		case "items":
+			l.ItemsPerSec = f
+		case "bytes":
+			l.BytesPerSec = f
+		case "burst":
+			if f == 0 {
+				return l, fmt.Errorf("rate limit %q: burst must be positive", part)
+			}
+			l.BurstSeconds = f
+		default:
+			return l, fmt.Errorf("rate limit %q: unknown key %q", part, k)
+		}
+	}
+	return l, nil
+}
+
+// parseTenantOverrides parses "acme:rps=100,items=5000;beta:rps=5".
+func parseTenantOverrides(spec string) (map[string]RateLimit, error) {
+	out := make(map[string]RateLimit)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, limits, ok := strings.Cut(entry, ":")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("rate limit override %q: expected tenant:limits", entry)
+		}
+		l, err := ParseRateLimit(limits)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit override for %s: %w", tenant, err)
+		}
+		out[tenant] = l
+	}
+	return out, nil
+}
+
+func loadRateLimits(errs *[]error) RateLimitConfig {
+	var c RateLimitConfig
+	for _, scope := range []struct {
+		key string
+		dst *RateLimit
+	}{
+		{"RATE_LIMIT_TENANT", &c.Tenant},
+		{"RATE_LIMIT_TOKEN", &c.Token},
+		{"RATE_LIMIT_IP", &c.IP},
+	} {
+		l, err := ParseRateLimit(getEnv(scope.key, ""))
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", scope.key, err))
+		}
+		*scope.dst = l
+	}
+	overrides, err := parseTenantOverrides(getEnv("RATE_LIMIT_TENANT_OVERRIDES", ""))
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("RATE_LIMIT_TENANT_OVERRIDES: %w", err))
+	}
+	c.TenantOverrides = overrides
+	return c
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SinkRoute sends items matching all of its non-empty lists to Sinks.
+type SinkRoute struct {
+	Tenants      []string
+	Apps         []string
+	Environments []string
+	Kinds        []string
+	Sinks        []string
+}
+
+var itemKinds = map[string]bool{"log": true, "event": true, "measurement": true, "exception": true}
+
+// ParseSinkRoutes parses "kind=exception->otlp;tenant=acme|beta,env=prod->loki,otlp;*->loki".
+// Routes are tried in order and the first match wins; "*" matches every item.
+// Matcher keys are tenant, app, env and kind; alternatives are separated by "|".
+func ParseSinkRoutes(spec string) ([]SinkRoute, error) {
+	var routes []SinkRoute
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		match, sinks, ok := strings.Cut(part, "->")
+		if !ok {
+			return nil, fmt.Errorf("route %q: expected <match>-><sinks>", part)
+		}
+		var r SinkRoute
+		for _, s := range strings.Split(sinks, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				r.Sinks = append(r.Sinks, s)
+			}
+		}
+		if len(r.Sinks) == 0 {
+			return nil, fmt.Errorf("route %q: no sinks", part)
+		}
+		if match = strings.TrimSpace(match); match != "*" {
+			for _, m := range strings.Split(match, ",") {
+				k, v, ok := strings.Cut(strings.TrimSpace(m), "=")
+				if !ok {
+					return nil, fmt.Errorf("route %q: expected key=value in %q", part, m)
+				}
+				var values []string
+				for _, s := range strings.Split(v, "|") {
+					if s = strings.TrimSpace(s); s != "" {
+						values = append(values, s)
+					}
+				}
+				switch strings.TrimSpace(k) {
+				case "tenant":
+					r.Tenants = append(r.Tenants, values...)
+				case "app":
+					r.Apps = append(r.Apps, values...)
+				case "env", "environment":
+					r.Environments = append(r.Environments, values...)
+				case "kind":
+					for _, kind := range values {
+						if !itemKinds[kind] {
+							return nil, fmt.Errorf("route %q: unknown kind %q", part, kind)
+						}
+					}
+					r.Kinds = append(r.Kinds, values...)
+				default:
+					return nil, fmt.Errorf("route %q: unknown key %q", part, k)
+				}
+			}
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
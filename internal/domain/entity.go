@@ -1,6 +1,10 @@
 package domain
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
 
 // Payload represents the Faro Web SDK v2.x transport payload.
 // Aligned with Grafana Faro: logs, events, measurements, exceptions.
@@ -104,43 +108,91 @@ type LokiStream struct {
 	Values [][]string        `json:"values"`
 }
 
-// UnmarshalJSON custom unmarshal to capture unknown meta fields (Faro SDK extensibility).
+// ItemCount returns the number of logs, events, measurements and exceptions in the payload.
+func (p *Payload) ItemCount() int {
+	return len(p.Logs) + len(p.Events) + len(p.Measurements) + len(p.Exceptions)
+}
+
+// UnmarshalJSON requires the meta key, as Faro always sends it; the rest decodes as usual.
 func (p *Payload) UnmarshalJSON(data []byte) error {
 	type alias Payload
-	aux := &struct {
+	aux := struct {
 		*alias
-		Meta json.RawMessage `json:"meta"`
-	}{
-		alias: (*alias)(p),
-	}
+		Meta metaField `json:"meta"`
+	}{alias: (*alias)(p), Meta: metaField{meta: &p.Meta}}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	var metaMap map[string]interface{}
-	if err := json.Unmarshal(aux.Meta, &metaMap); err != nil {
+	if !aux.Meta.present {
+		return errors.New("meta: missing")
+	}
+	return nil
+}
+
+// metaField decodes into meta and records that the key was present, even when it is null.
+type metaField struct {
+	meta    *Meta
+	present bool
+}
+
+func (f *metaField) UnmarshalJSON(data []byte) error {
+	f.present = true
+	return f.meta.UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes meta in a single pass over its keys: known sections go straight
+// into their typed fields and unknown ones are kept in Extra (Faro SDK extensibility).
+// Sections with an unexpected shape are skipped rather than failing the whole payload.
+func (m *Meta) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
 		return err
 	}
-	p.Meta.Extra = make(map[string]interface{})
-	for k, v := range metaMap {
-		fieldBytes, _ := json.Marshal(v)
-		switch k {
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return errors.New("meta: expected object")
+	}
+	m.Extra = make(map[string]interface{})
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		var target interface{}
+		switch key {
 		case "app":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.App)
+			target = &m.App
 		case "browser":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.Browser)
+			target = &m.Browser
 		case "view":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.View)
+			target = &m.View
 		case "page":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.Page)
+			target = &m.Page
 		case "session":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.Session)
+			target = &m.Session
 		case "sdk":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.SDK)
+			target = &m.SDK
 		case "user":
-			_ = json.Unmarshal(fieldBytes, &p.Meta.User)
+			target = &m.User
 		default:
-			p.Meta.Extra[k] = v
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			m.Extra[key] = v
+			continue
+		}
+		if err := dec.Decode(target); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return err
+			}
 		}
 	}
-	return nil
+	_, err = dec.Token()
+	return err
 }
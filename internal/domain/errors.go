@@ -3,8 +3,13 @@ package domain
 import "errors"
 
 var (
-	ErrInvalidPayload   = errors.New("invalid payload")
-	ErrMissingTenant    = errors.New("tenant or token not provided")
-	ErrEmptyPayload     = errors.New("payload has no data")
-	ErrLokiSend         = errors.New("failed to send to Loki")
+	ErrInvalidPayload = errors.New("invalid payload")
+	ErrMissingTenant  = errors.New("tenant or token not provided")
+	ErrEmptyPayload   = errors.New("payload has no data")
+	ErrSinkSend       = errors.New("failed to write to sink")
+	ErrLimitExceeded  = errors.New("payload exceeds limits")
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	// ErrSinkRejected wraps a sink answer that rejects the items themselves (4xx, including
+	// rate limiting), as opposed to the sink being unreachable or failing.
+	ErrSinkRejected = errors.New("sink rejected the items")
 )
@@ -0,0 +1,108 @@
+package domain
+
+import "fmt"
+
+// Item kinds, one per Faro payload section.
+const (
+	KindLog         = "log"
+	KindEvent       = "event"
+	KindMeasurement = "measurement"
+	KindException   = "exception"
+)
+
+// Item is one Faro log, event, measurement or exception ready to be written to a sink.
+type Item struct {
+	Kind string
+	// Timestamp is the item timestamp as sent by the SDK (RFC 3339); may be empty.
+	Timestamp string
+	// Meta is shared by all items of a payload, with top-level page/session already merged in.
+	Meta *Meta
+	// Fields are the item's own flattened fields (level, message, event_name, measurement_value_*, ...).
+	Fields map[string]interface{}
+}
+
+// Message is a short human-readable summary of the item (log message, event name, exception text...).
+func (it Item) Message() string {
+	switch it.Kind {
+	case KindLog:
+		s, _ := it.Fields["message"].(string)
+		return s
+	case KindEvent:
+		s, _ := it.Fields["event_name"].(string)
+		return s
+	case KindMeasurement:
+		s, _ := it.Fields["measurement_type"].(string)
+		return s
+	case KindException:
+		t, _ := it.Fields["exception_type"].(string)
+		v, _ := it.Fields["exception_value"].(string)
+		if t == "" {
+			return v
+		}
+		return t + ": " + v
+	}
+	return ""
+}
+
+// Level is the log level of a log item, "error" for exceptions and "info" otherwise.
+func (it Item) Level() string {
+	switch it.Kind {
+	case KindLog:
+		if s, _ := it.Fields["level"].(string); s != "" {
+			return s
+		}
+	case KindException:
+		return "error"
+	}
+	return "info"
+}
+
+// AllFields returns the meta fields merged with the item's own fields.
+func (it Item) AllFields() map[string]interface{} {
+	fields := it.Meta.Fields()
+	for k, v := range it.Fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// Fields flattens the meta into the key/values sent with every item
+// (app, browser_*, session_id, page_url, user_attr_*, and unknown meta sections).
+func (m *Meta) Fields() map[string]interface{} {
+	if m == nil {
+		m = &Meta{}
+	}
+	fields := map[string]interface{}{
+		"app":             m.App.Name,
+		"app_version":     m.App.Version,
+		"environment":     m.App.Environment,
+		"browser_name":    m.Browser.Name,
+		"browser_version": m.Browser.Version,
+		"browser_os":      m.Browser.OS,
+		"browser_mobile":  m.Browser.Mobile,
+		"session_id":      m.Session.ID,
+		"page_url":        m.Page.URL,
+		"view_name":       m.View.Name,
+		"sdk_version":     m.SDK.Version,
+		"user_username":   m.User.Username,
+	}
+	for k, v := range m.User.Attributes {
+		fields[fmt.Sprintf("user_attr_%s", k)] = v
+	}
+	for k, v := range m.Extra {
+		if k == "user" {
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for sk, sv := range val {
+				fields[fmt.Sprintf("%s_%s", k, sk)] = sv
+			}
+		case []interface{}:
+			fields[k] = val
+		default:
+			fields[k] = v
+		}
+	}
+	return fields
+}
@@ -0,0 +1,171 @@
+package domain
+
+import "fmt"
+
+// Limits bounds the size of a decoded payload. Zero values are unlimited.
+type Limits struct {
+	MaxItemsPerType int // logs, events, measurements and exceptions, each
+	MaxStringLength int // bytes in any string value
+	MaxMapEntries   int // entries in attributes, values and meta maps
+}
+
+// CheckLimits returns an error wrapping ErrLimitExceeded for the first limit the payload breaks.
+func (p *Payload) CheckLimits(l Limits) error {
+	if l.MaxItemsPerType > 0 {
+		for _, kind := range []struct {
+			name string
+			n    int
+		}{
+			{"logs", len(p.Logs)},
+			{"events", len(p.Events)},
+			{"measurements", len(p.Measurements)},
+			{"exceptions", len(p.Exceptions)},
+		} {
+			if kind.n > l.MaxItemsPerType {
+				return fmt.Errorf("%w: %d %s (max %d)", ErrLimitExceeded, kind.n, kind.name, l.MaxItemsPerType)
+			}
+		}
+	}
+	c := limitChecker{l}
+	if err := c.meta(&p.Meta); err != nil {
+		return err
+	}
+	if p.Page != nil {
+		if err := c.str("page url", p.Page.URL); err != nil {
+			return err
+		}
+	}
+	if p.Session != nil {
+		if err := c.str("session id", p.Session.ID); err != nil {
+			return err
+		}
+	}
+	for i := range p.Logs {
+		lg := &p.Logs[i]
+		if err := c.fields(
+			field{"log message", lg.Message}, field{"log level", lg.Level},
+			field{"log kind", lg.Kind}, field{"log timestamp", lg.Timestamp},
+		); err != nil {
+			return err
+		}
+	}
+	for i := range p.Events {
+		ev := &p.Events[i]
+		if err := c.fields(
+			field{"event name", ev.Name}, field{"event domain", ev.Domain}, field{"event timestamp", ev.Timestamp},
+		); err != nil {
+			return err
+		}
+		if err := c.value("event attributes", ev.Attributes); err != nil {
+			return err
+		}
+	}
+	for i := range p.Measurements {
+		m := &p.Measurements[i]
+		if err := c.fields(field{"measurement type", m.Type}, field{"measurement timestamp", m.Timestamp}); err != nil {
+			return err
+		}
+		if err := c.size("measurement values", len(m.Values)); err != nil {
+			return err
+		}
+		for k := range m.Values {
+			if err := c.str("measurement value name", k); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range p.Exceptions {
+		ex := &p.Exceptions[i]
+		if err := c.fields(
+			field{"exception value", ex.Value}, field{"exception type", ex.Type}, field{"exception timestamp", ex.Timestamp},
+		); err != nil {
+			return err
+		}
+		if err := c.size("stack frames", len(ex.Stacktrace.Frames)); err != nil {
+			return err
+		}
+		for _, f := range ex.Stacktrace.Frames {
+			if err := c.fields(field{"stack frame filename", f.Filename}, field{"stack frame function", f.Function}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// field names a string value for limit errors.
+type field struct {
+	where, s string
+}
+
+type limitChecker struct {
+	l Limits
+}
+
+func (c limitChecker) meta(m *Meta) error {
+	for _, s := range []string{
+		m.App.Name, m.App.Version, m.App.Environment, m.Browser.Name, m.Browser.Version, m.Browser.OS,
+		m.View.Name, m.Page.URL, m.Session.ID, m.SDK.Version, m.User.Username,
+	} {
+		if err := c.str("meta", s); err != nil {
+			return err
+		}
+	}
+	if err := c.value("user attributes", m.User.Attributes); err != nil {
+		return err
+	}
+	return c.value("meta", m.Extra)
+}
+
+// value walks decoded JSON (maps, slices, strings) and checks every string and map/slice size.
+func (c limitChecker) value(where string, v interface{}) error {
+	switch val := v.(type) {
+	case string:
+		return c.str(where, val)
+	case map[string]interface{}:
+		if err := c.size(where, len(val)); err != nil {
+			return err
+		}
+		for k, sv := range val {
+			if err := c.str(where, k); err != nil {
+				return err
+			}
+			if err := c.value(where, sv); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if err := c.size(where, len(val)); err != nil {
+			return err
+		}
+		for _, sv := range val {
+			if err := c.value(where, sv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c limitChecker) fields(fs ...field) error {
+	for _, f := range fs {
+		if err := c.str(f.where, f.s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c limitChecker) str(where, s string) error {
+	if c.l.MaxStringLength > 0 && len(s) > c.l.MaxStringLength {
+		return fmt.Errorf("%w: %s string of %d bytes (max %d)", ErrLimitExceeded, where, len(s), c.l.MaxStringLength)
+	}
+	return nil
+}
+
+func (c limitChecker) size(where string, n int) error {
+	if c.l.MaxMapEntries > 0 && n > c.l.MaxMapEntries {
+		return fmt.Errorf("%w: %s has %d entries (max %d)", ErrLimitExceeded, where, n, c.l.MaxMapEntries)
+	}
+	return nil
+}
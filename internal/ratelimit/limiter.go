@@ -0,0 +1,212 @@
+// Package ratelimit applies token-bucket limits and daily quotas per tenant, token and client IP.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sync"
+	"time"
+
+	"collector-fe-instrumentation/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	ScopeTenant = "tenant"
+	ScopeToken  = "token"
+	ScopeIP     = "ip"
+
+	idleTTL       = 10 * time.Minute
+	sweepInterval = time.Minute
+)
+
+// Keys identifies the caller in each scope; empty keys are not limited.
+type Keys struct {
+	Tenant string
+	Token  string
+	IP     string
+}
+
+// Decision is the outcome of a limit check.
+type Decision struct {
+	Allowed bool
+	// Scope is the scope that rejected the request.
+	Scope string
+	// Quota is true when the daily quota, rather than a rate, was exceeded.
+	Quota      bool
+	RetryAfter time.Duration
+}
+
+// Limiter holds the buckets for every active key. It is safe for concurrent use.
+type Limiter struct {
+	cfg config.RateLimitConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	limit    config.RateLimit
+	requests *rate.Limiter // nil when unlimited
+	items    *rate.Limiter
+	bytes    *rate.Limiter
+	day      string
+	dayItems int64
+	lastSeen time.Time
+}
+
+// New creates a Limiter from config.
+func New(cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{cfg: cfg, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// AllowRequest takes one request from each scope's request bucket.
+func (l *Limiter) AllowRequest(keys Keys) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	var taken []*rate.Reservation
+	for _, s := range l.scopes(keys, now) {
+		if d, ok := reserve(s.entry.requests, 1, now, s.name, &taken); !ok {
+			return d
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// AllowUsage takes items and bytes from each scope's buckets and counts items towards the daily quota.
+// Nothing is consumed when any scope rejects. A batch larger than a bucket drains the whole bucket.
+func (l *Limiter) AllowUsage(keys Keys, items, bytes int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	day := now.UTC().Format(time.DateOnly)
+
+	var taken []*rate.Reservation
+	scopes := l.scopes(keys, now)
+	for _, s := range scopes {
+		if d, ok := reserve(s.entry.items, items, now, s.name, &taken); !ok {
+			return d
+		}
+		if d, ok := reserve(s.entry.bytes, bytes, now, s.name, &taken); !ok {
+			return d
+		}
+		if s.entry.limit.DailyItems > 0 {
+			used := s.entry.dayItems
+			if s.entry.day != day {
+				used = 0
+			}
+			if used+int64(items) > s.entry.limit.DailyItems {
+				cancelAll(taken, now)
+				return Decision{Scope: s.name, Quota: true, RetryAfter: untilNextDay(now)}
+			}
+		}
+	}
+	for _, s := range scopes {
+		if s.entry.limit.DailyItems > 0 {
+			if s.entry.day != day {
+				s.entry.day, s.entry.dayItems = day, 0
+			}
+			s.entry.dayItems += int64(items)
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+type scopedEntry struct {
+	name  string
+	entry *entry
+}
+
+func (l *Limiter) scopes(keys Keys, now time.Time) []scopedEntry {
+	var out []scopedEntry
+	if keys.Tenant != "" {
+		if limit := l.cfg.ForTenant(keys.Tenant); limit.Enabled() {
+			out = append(out, scopedEntry{ScopeTenant, l.entry(ScopeTenant+":"+keys.Tenant, limit, now)})
+		}
+	}
+	if keys.Token != "" && l.cfg.Token.Enabled() {
+		sum := sha256.Sum256([]byte(keys.Token))
+		out = append(out, scopedEntry{ScopeToken, l.entry(ScopeToken+":"+hex.EncodeToString(sum[:16]), l.cfg.Token, now)})
+	}
+	if keys.IP != "" && l.cfg.IP.Enabled() {
+		out = append(out, scopedEntry{ScopeIP, l.entry(ScopeIP+":"+keys.IP, l.cfg.IP, now)})
+	}
+	return out
+}
+
+func (l *Limiter) entry(key string, limit config.RateLimit, now time.Time) *entry {
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry{
+			limit:    limit,
+			requests: newBucket(limit.RequestsPerSec, limit.BurstSeconds),
+			items:    newBucket(limit.ItemsPerSec, limit.BurstSeconds),
+			bytes:    newBucket(limit.BytesPerSec, limit.BurstSeconds),
+		}
+		l.entries[key] = e
+	}
+	e.lastSeen = now
+	return e
+}
+
+// sweep drops idle entries, keeping those that still carry today's quota usage.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	day := now.UTC().Format(time.DateOnly)
+	for k, e := range l.entries {
+		if now.Sub(e.lastSeen) > idleTTL && (e.dayItems == 0 || e.day != day) {
+			delete(l.entries, k)
+		}
+	}
+}
+
+func newBucket(perSec, burstSeconds float64) *rate.Limiter {
+	if perSec <= 0 {
+		return nil
+	}
+	burst := int(math.Ceil(perSec * burstSeconds))
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(perSec), burst)
+}
+
+// reserve takes n tokens from b. On rejection it cancels every reservation in taken.
+func reserve(b *rate.Limiter, n int, now time.Time, scope string, taken *[]*rate.Reservation) (Decision, bool) {
+	if b == nil || n <= 0 {
+		return Decision{}, true
+	}
+	if n > b.Burst() {
+		n = b.Burst()
+	}
+	r := b.ReserveN(now, n)
+	if delay := r.DelayFrom(now); !r.OK() || delay > 0 {
+		r.CancelAt(now)
+		cancelAll(*taken, now)
+		return Decision{Scope: scope, RetryAfter: delay}, false
+	}
+	*taken = append(*taken, r)
+	return Decision{}, true
+}
+
+func cancelAll(taken []*rate.Reservation, now time.Time) {
+	for _, r := range taken {
+		r.CancelAt(now)
+	}
+}
+
+func untilNextDay(now time.Time) time.Duration {
+	utc := now.UTC()
+	next := time.Date(utc.Year(), utc.Month(), utc.Day()+1, 0, 0, 0, 0, time.UTC)
+	return next.Sub(utc)
+}
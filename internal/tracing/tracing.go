@@ -0,0 +1,395 @@
+// Package tracing records spans of the collector's own work and hands them to an Exporter in batches.
+// Spans travel in the context: Start only records when the context already holds a span started by a Tracer,
+// so code paths without tracing pay nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader and TracestateHeader carry the W3C trace context.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTraceState is the tracestate length vendors must propagate; longer values are dropped.
+const maxTraceState = 512
+
+// Span kinds, as in OTLP.
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// TraceID and SpanID are the W3C/OTLP identifiers.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// TraceState is the incoming tracestate, passed on unchanged.
+	TraceState string
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent parses a traceparent header value; ok is false for malformed or all-zero IDs.
+func ParseTraceparent(s string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Extract reads the trace context of an incoming request; ok is false without a valid traceparent.
+func Extract(h http.Header) (sc SpanContext, ok bool) {
+	sc, ok = ParseTraceparent(h.Get(TraceparentHeader))
+	if !ok {
+		return SpanContext{}, false
+	}
+	if ts := strings.TrimSpace(strings.Join(h.Values(TracestateHeader), ",")); len(ts) <= maxTraceState {
+		sc.TraceState = ts
+	}
+	return sc, true
+}
+
+// Attribute is a span attribute; Value is a string, bool, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(k, v string) Attribute {
+	return Attribute{Key: k, Value: v}
+}
+
+// Int returns an integer attribute.
+func Int(k string, v int) Attribute {
+	return Attribute{Key: k, Value: int64(v)}
+}
+
+// SpanData is a finished span as handed to the Exporter.
+type SpanData struct {
+	SpanContext
+	Parent     SpanID
+	Name       string
+	Kind       int
+	Start, End time.Time
+	Attributes []Attribute
+	// Error is the status message of a failed span; empty means the span did not fail.
+	Error string
+}
+
+// Exporter sends finished spans to a backend.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+// Options configures a Tracer. Zero values use the defaults.
+type Options struct {
+	// SampleRatio is the share of requests recorded, from 0 to 1. New traces are sampled on their random
+	// trace ID; for an incoming traceparent both the sampled flag and the client-chosen trace ID are
+	// ignored and a fresh random value decides, so clients cannot force their requests to be recorded.
+	SampleRatio float64
+	// BatchSize spans trigger an export before Interval (default 512).
+	BatchSize int
+	// QueueSize bounds the spans waiting for export; newer spans are dropped beyond it (default 2048).
+	QueueSize int
+	// Interval between exports (default 5s).
+	Interval time.Duration
+	Log      *slog.Logger
+}
+
+// Tracer starts root spans and exports finished spans in the background.
+type Tracer struct {
+	exporter  Exporter
+	threshold uint64
+	batchSize int
+	queueSize int
+	interval  time.Duration
+	log       *slog.Logger
+
+	mu      sync.Mutex
+	pending []SpanData
+	dropped int
+	full    chan struct{}
+}
+
+// NewTracer creates a Tracer; call Run to export and Flush on shutdown.
+func NewTracer(exporter Exporter, opts Options) *Tracer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.Log == nil {
+		opts.Log = slog.Default()
+	}
+	return &Tracer{
+		exporter:  exporter,
+		threshold: ratioThreshold(opts.SampleRatio),
+		batchSize: opts.BatchSize,
+		queueSize: opts.QueueSize,
+		interval:  opts.Interval,
+		log:       opts.Log,
+		full:      make(chan struct{}, 1),
+	}
+}
+
+// ratioThreshold maps a ratio to the bound compared against the low 63 bits of the trace ID.
+func ratioThreshold(ratio float64) uint64 {
+	switch {
+	case ratio <= 0:
+		return 0
+	case ratio >= 1:
+		return math.MaxUint64
+	default:
+		return uint64(ratio * (1 << 63))
+	}
+}
+
+// Start begins a root span for work received from outside, continuing remote when it is valid.
+// Whether the span is sampled depends only on SampleRatio, never on remote.Sampled: a new trace is
+// sampled on its trace ID, a remote one on a fresh random value, since the client picks its trace ID.
+func (t *Tracer) Start(ctx context.Context, name string, kind int, remote SpanContext, attrs ...Attribute) (context.Context, *Span) {
+	sc, parent := SpanContext{TraceID: newTraceID()}, SpanID{}
+	v := binary.BigEndian.Uint64(sc.TraceID[8:])
+	if remote.IsValid() {
+		sc, parent = remote, remote.SpanID
+		v = randomUint64()
+	}
+	sc.Sampled = t.threshold == math.MaxUint64 || v>>1 < t.threshold
+	return t.start(ctx, sc, parent, name, kind, attrs)
+}
+
+func (t *Tracer) start(ctx context.Context, sc SpanContext, parent SpanID, name string, kind int, attrs []Attribute) (context.Context, *Span) {
+	sc.SpanID = newSpanID()
+	s := &Span{tracer: t, data: SpanData{SpanContext: sc, Parent: parent, Name: name, Kind: kind, Start: time.Now()}}
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+	return ContextWithSpan(ctx, s), s
+}
+
+// Run exports every Interval, or sooner when BatchSize spans are waiting, until ctx is done.
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.full:
+		}
+		if err := t.export(ctx, t.batchSize); err != nil {
+			t.log.Warn("tracing: export failed", "error", err)
+		}
+	}
+}
+
+// Flush exports every waiting span.
+func (t *Tracer) Flush(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		n := len(t.pending)
+		t.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		if err := t.export(ctx, t.batchSize); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *Tracer) export(ctx context.Context, limit int) error {
+	t.mu.Lock()
+	n := min(len(t.pending), limit)
+	batch := t.pending[:n:n]
+	t.pending = t.pending[n:]
+	dropped := t.dropped
+	t.dropped = 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.log.Warn("tracing: queue full, spans dropped", "spans", dropped)
+	}
+	if n == 0 {
+		return nil
+	}
+	return t.exporter.ExportSpans(ctx, batch)
+}
+
+func (t *Tracer) enqueue(d SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= t.queueSize {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, d)
+	if len(t.pending) >= t.batchSize {
+		select {
+		case t.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Span is an operation in progress. Every method is safe on a nil *Span, which is what Start
+// returns when the context is not traced.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span's identifiers.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.data.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed; a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil || !s.data.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export when sampled. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	d := s.data
+	s.mu.Unlock()
+	if d.Sampled {
+		s.tracer.enqueue(d)
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx carrying s, e.g. to continue a request's trace on a queue worker.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start begins a child of the span in ctx; without one it returns ctx and a nil span.
+func Start(ctx context.Context, name string, kind int, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	sc := parent.data.SpanContext
+	return parent.tracer.start(ctx, sc, sc.SpanID, name, kind, attrs)
+}
+
+// Inject sets the traceparent and tracestate headers for the span in ctx, if any.
+func Inject(ctx context.Context, h http.Header) {
+	if s := SpanFromContext(ctx); s != nil {
+		h.Set(TraceparentHeader, s.data.Traceparent())
+		if s.data.TraceState != "" {
+			h.Set(TracestateHeader, s.data.TraceState)
+		}
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func randomUint64() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker.Write while the sink is considered down.
+var ErrCircuitOpen = errors.New("sink circuit open")
+
+// Circuit breaker states reported by CircuitBreaker.State.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerOption configures a CircuitBreaker.
+type BreakerOption func(*CircuitBreaker)
+
+// WithBreakerKey gives each key returned by key its own circuit, e.g. the endpoint a tenant writes
+// to, so one failing endpoint does not stop the writes to the others. Without it the sink has a
+// single circuit.
+func WithBreakerKey(key func(tenantID string) string) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.key = key
+	}
+}
+
+// CircuitBreaker is a Sink that stops calling a failing sink. After failures consecutive errors it
+// fails fast for cooldown, then lets one trial write through: success closes it, failure reopens it.
+// Only network errors and 5xx answers count as failures; see Write.
+type CircuitBreaker struct {
+	next     Sink
+	failures int
+	cooldown time.Duration
+	key      func(tenantID string) string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the breaker state of one key.
+type circuit struct {
+	consecutive int
+	open        bool
+	openedAt    time.Time
+	trial       bool
+}
+
+// NewCircuitBreaker wraps next; failures <= 0 disables the breaker.
+func NewCircuitBreaker(next Sink, failures int, cooldown time.Duration, opts ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		next:     next,
+		failures: failures,
+		cooldown: cooldown,
+		key:      func(string) string { return "" },
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+	}
+	for _, fn := range opts {
+		fn(b)
+	}
+	return b
+}
+
+// Write implements Sink. Caller cancellations, unknown tenants and items the sink rejected
+// (domain.ErrSinkRejected, e.g. a 400 or 429 answer) do not count as sink failures.
+func (b *CircuitBreaker) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	key := b.key(tenantID)
+	if !b.allow(key) {
+		return ErrCircuitOpen
+	}
+	err := b.next.Write(ctx, tenantID, items)
+	if err != nil && (ctx.Err() != nil || errors.Is(err, domain.ErrUnknownTenant) || errors.Is(err, domain.ErrSinkRejected)) {
+		b.release(key)
+		return err
+	}
+	b.record(key, err)
+	return err
+}
+
+// State returns closed while any circuit is closed, since the sink still takes some writes;
+// otherwise half-open once a circuit's cooldown has elapsed and a trial write is allowed, or open.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.circuits) == 0 {
+		return BreakerClosed
+	}
+	state := BreakerOpen
+	for _, c := range b.circuits {
+		switch {
+		case !c.open:
+			return BreakerClosed
+		case b.now().Sub(c.openedAt) >= b.cooldown:
+			state = BreakerHalfOpen
+		}
+	}
+	return state
+}
+
+// circuit returns the state of key, creating it closed. b.mu must be held.
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) allow(key string) bool {
+	if b.failures <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	if !c.open {
+		return true
+	}
+	if c.trial || b.now().Sub(c.openedAt) < b.cooldown {
+		return false
+	}
+	c.trial = true
+	return true
+}
+
+// release ends a trial write without judging the sink.
+func (b *CircuitBreaker) release(key string) {
+	if b.failures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.circuit(key).trial = false
+	b.mu.Unlock()
+}
+
+func (b *CircuitBreaker) record(key string, err error) {
+	if b.failures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	c.trial = false
+	if err == nil {
+		c.consecutive = 0
+		c.open = false
+		return
+	}
+	c.consecutive++
+	if c.open || c.consecutive >= b.failures {
+		c.open = true
+		c.openedAt = b.now()
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
 
 	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
 )
 
 // LokiWriter sends log streams to Loki (interface for clean architecture).
@@ -14,20 +14,83 @@ type LokiWriter interface {
 	Push(ctx context.Context, tenantID string, streams []domain.LokiStream) error
 }
 
-// CollectorService implements the collect-logs use case (Faro → Loki).
+// Sink writes the items of one payload to a backend (Loki, OTLP, ...).
+type Sink interface {
+	Write(ctx context.Context, tenantID string, items []domain.Item) error
+}
+
+// Recorder receives item counts for the collector's own metrics.
+type Recorder interface {
+	ItemsReceived(tenant, kind string, n int)
+	ItemsDropped(tenant, reason string, n int)
+}
+
+// PayloadObserver sees every payload that was delivered (e.g. to derive RUM metrics).
+type PayloadObserver interface {
+	ObservePayload(tenant string, p *domain.Payload)
+}
+
+// Reasons passed to Recorder.ItemsDropped.
+const (
+	DropReasonLimits    = "payload_too_large"
+	DropReasonSinkError = "sink_error"
+)
+
+// CollectorService implements the collect-logs use case (Faro → sink, Loki by default).
 type CollectorService struct {
-	loki LokiWriter
-	log  *slog.Logger
+	sink      Sink
+	log       *slog.Logger
+	limits    domain.Limits
+	recorder  Recorder
+	observers []PayloadObserver
+}
+
+// ServiceOption configures the CollectorService.
+type ServiceOption func(*CollectorService)
+
+// WithLimits rejects payloads that exceed l (zero fields are unlimited).
+func WithLimits(l domain.Limits) ServiceOption {
+	return func(s *CollectorService) {
+		s.limits = l
+	}
+}
+
+// WithSink writes items to sink instead of the Loki writer given to NewCollectorService.
+func WithSink(sink Sink) ServiceOption {
+	return func(s *CollectorService) {
+		s.sink = sink
+	}
+}
+
+// WithRecorder reports received and dropped items to r.
+func WithRecorder(r Recorder) ServiceOption {
+	return func(s *CollectorService) {
+		s.recorder = r
+	}
+}
+
+// WithObserver adds o to the observers notified after each delivered payload.
+func WithObserver(o PayloadObserver) ServiceOption {
+	return func(s *CollectorService) {
+		s.observers = append(s.observers, o)
+	}
 }
 
-func NewCollectorService(loki LokiWriter, log *slog.Logger) *CollectorService {
+func NewCollectorService(loki LokiWriter, log *slog.Logger, opts ...ServiceOption) *CollectorService {
 	if log == nil {
 		log = slog.Default()
 	}
-	return &CollectorService{loki: loki, log: log}
+	s := &CollectorService{log: log}
+	if loki != nil {
+		s.sink = NewLokiSink(loki)
+	}
+	for _, fn := range opts {
+		fn(s)
+	}
+	return s
 }
 
-// Collect validates the payload, converts it to Loki streams, and pushes to Loki.
+// Collect validates the payload, converts it to items, and writes them to the sink.
 func (s *CollectorService) Collect(ctx context.Context, tenantID string, payload *domain.Payload) error {
 	if payload == nil {
 		return domain.ErrInvalidPayload
@@ -35,110 +98,101 @@ func (s *CollectorService) Collect(ctx context.Context, tenantID string, payload
 	if tenantID == "" {
 		return domain.ErrMissingTenant
 	}
+	if err := payload.CheckLimits(s.limits); err != nil {
+		s.dropped(tenantID, DropReasonLimits, payload.ItemCount())
+		return err
+	}
 
-	streams := s.payloadToStreams(payload)
-	if len(streams) == 0 {
+	_, span := tracing.Start(ctx, "collector.payload_to_items", tracing.KindInternal)
+	items := s.payloadToItems(payload)
+	span.SetAttributes(tracing.Int("items", len(items)))
+	span.End()
+	if len(items) == 0 {
 		return domain.ErrEmptyPayload
 	}
 
-	if err := s.loki.Push(ctx, tenantID, streams); err != nil {
-		s.log.ErrorContext(ctx, "loki push failed", "error", err, "tenant", tenantID)
-		return fmt.Errorf("%w: %v", domain.ErrLokiSend, err)
+	sinkCtx, span := tracing.Start(ctx, "sink.write", tracing.KindInternal, tracing.Int("items", len(items)))
+	err := s.sink.Write(sinkCtx, tenantID, items)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		s.log.ErrorContext(ctx, "sink write failed", "error", err, "tenant", tenantID)
+		s.dropped(tenantID, DropReasonSinkError, len(items))
+		return fmt.Errorf("%w: %w", domain.ErrSinkSend, err)
+	}
+	if s.recorder != nil {
+		s.recorder.ItemsReceived(tenantID, "log", len(payload.Logs))
+		s.recorder.ItemsReceived(tenantID, "event", len(payload.Events))
+		s.recorder.ItemsReceived(tenantID, "measurement", len(payload.Measurements))
+		s.recorder.ItemsReceived(tenantID, "exception", len(payload.Exceptions))
+	}
+	for _, o := range s.observers {
+		o.ObservePayload(tenantID, payload)
 	}
 	return nil
 }
 
-func (s *CollectorService) payloadToStreams(p *domain.Payload) []domain.LokiStream {
-	base := s.baseFields(p)
-	var streams []domain.LokiStream
+func (s *CollectorService) dropped(tenantID, reason string, n int) {
+	if s.recorder != nil {
+		s.recorder.ItemsDropped(tenantID, reason, n)
+	}
+}
+
+func (s *CollectorService) payloadToItems(p *domain.Payload) []domain.Item {
+	meta := p.Meta
+	if meta.Page.URL == "" && p.Page != nil {
+		meta.Page.URL = p.Page.URL
+	}
+	if meta.Session.ID == "" && p.Session != nil {
+		meta.Session.ID = p.Session.ID
+	}
+	items := make([]domain.Item, 0, p.ItemCount())
 
 	for _, e := range p.Logs {
-		fields := copyMap(base)
-		fields["kind"] = logKind(e.Level)
-		fields["level"] = e.Level
-		fields["message"] = e.Message
-		streams = append(streams, toLokiStream(fields))
+		items = append(items, domain.Item{Kind: domain.KindLog, Timestamp: e.Timestamp, Meta: &meta, Fields: map[string]interface{}{
+			"kind":    logKind(e.Level),
+			"level":   e.Level,
+			"message": e.Message,
+		}})
 	}
 	for _, e := range p.Events {
-		fields := copyMap(base)
-		fields["kind"] = "event"
-		fields["event_name"] = e.Name
-		fields["event_domain"] = e.Domain
-		fields["event_timestamp"] = e.Timestamp
+		fields := map[string]interface{}{
+			"kind":            "event",
+			"event_name":      e.Name,
+			"event_domain":    e.Domain,
+			"event_timestamp": e.Timestamp,
+		}
 		for k, v := range e.Attributes {
 			fields[fmt.Sprintf("event_data_%s", k)] = v
 		}
-		streams = append(streams, toLokiStream(fields))
+		items = append(items, domain.Item{Kind: domain.KindEvent, Timestamp: e.Timestamp, Meta: &meta, Fields: fields})
 	}
 	for _, m := range p.Measurements {
-		fields := copyMap(base)
-		fields["kind"] = "measurement"
-		fields["measurement_type"] = m.Type
-		fields["measurement_timestamp"] = m.Timestamp
+		fields := map[string]interface{}{
+			"kind":                  "measurement",
+			"measurement_type":      m.Type,
+			"measurement_timestamp": m.Timestamp,
+		}
 		for k, v := range m.Values {
 			fields[fmt.Sprintf("measurement_value_%s", k)] = v
 		}
-		streams = append(streams, toLokiStream(fields))
+		items = append(items, domain.Item{Kind: domain.KindMeasurement, Timestamp: m.Timestamp, Meta: &meta, Fields: fields})
 	}
 	for _, ex := range p.Exceptions {
-		fields := copyMap(base)
-		fields["kind"] = "exception"
-		fields["exception_type"] = ex.Type
-		fields["exception_value"] = ex.Value
-		fields["exception_timestamp"] = ex.Timestamp
 		var stack []string
 		for _, f := range ex.Stacktrace.Frames {
 			stack = append(stack, fmt.Sprintf("%s:%s:%d:%d", f.Filename, f.Function, f.Lineno, f.Colno))
 		}
-		fields["exception_stacktrace"] = joinStrings(stack, " | ")
-		streams = append(streams, toLokiStream(fields))
-	}
-
-	return streams
-}
-
-func (s *CollectorService) baseFields(p *domain.Payload) map[string]interface{} {
-	pageURL := p.Meta.Page.URL
-	if pageURL == "" && p.Page != nil {
-		pageURL = p.Page.URL
-	}
-	sessionID := p.Meta.Session.ID
-	if sessionID == "" && p.Session != nil {
-		sessionID = p.Session.ID
-	}
-	fields := map[string]interface{}{
-		"app":             p.Meta.App.Name,
-		"app_version":     p.Meta.App.Version,
-		"environment":     p.Meta.App.Environment,
-		"browser_name":    p.Meta.Browser.Name,
-		"browser_version": p.Meta.Browser.Version,
-		"browser_os":      p.Meta.Browser.OS,
-		"browser_mobile":  p.Meta.Browser.Mobile,
-		"session_id":      sessionID,
-		"page_url":        pageURL,
-		"view_name":       p.Meta.View.Name,
-		"sdk_version":     p.Meta.SDK.Version,
-		"user_username":   p.Meta.User.Username,
-	}
-	for k, v := range p.Meta.User.Attributes {
-		fields[fmt.Sprintf("user_attr_%s", k)] = v
-	}
-	for k, v := range p.Meta.Extra {
-		if k == "user" {
-			continue
-		}
-		switch val := v.(type) {
-		case map[string]interface{}:
-			for sk, sv := range val {
-				fields[fmt.Sprintf("%s_%s", k, sk)] = sv
-			}
-		case []interface{}:
-			fields[k] = val
-		default:
-			fields[k] = v
-		}
+		items = append(items, domain.Item{Kind: domain.KindException, Timestamp: ex.Timestamp, Meta: &meta, Fields: map[string]interface{}{
+			"kind":                 "exception",
+			"exception_type":       ex.Type,
+			"exception_value":      ex.Value,
+			"exception_timestamp":  ex.Timestamp,
+			"exception_stacktrace": joinStrings(stack, " | "),
+		}})
 	}
-	return fields
+
+	return items
 }
 
 func logKind(level string) string {
@@ -172,35 +226,3 @@ func joinStrings(ss []string, sep string) string {
 	}
 	return out
 }
-
-func toLokiStream(fields map[string]interface{}) domain.LokiStream {
-	labels := map[string]string{
-		"app":         fmt.Sprint(fields["app"]),
-		"kind":        fmt.Sprint(fields["kind"]),
-		"level":       fmt.Sprint(fields["level"]),
-		"environment": fmt.Sprint(fields["environment"]),
-		"browser":     fmt.Sprint(fields["browser_name"]),
-		"session_id":  fmt.Sprint(fields["session_id"]),
-	}
-	line := formatLogLine(fields)
-	ts := fmt.Sprintf("%d", time.Now().UnixNano())
-	return domain.LokiStream{
-		Stream: labels,
-		Values: [][]string{{ts, line}},
-	}
-}
-
-func formatLogLine(fields map[string]interface{}) string {
-	var s string
-	for k, v := range fields {
-		switch val := v.(type) {
-		case string:
-			s += fmt.Sprintf("%s=%q ", k, val)
-		case bool, float64, int, int64:
-			s += fmt.Sprintf("%s=%v ", k, val)
-		default:
-			s += fmt.Sprintf("%s=%v ", k, v)
-		}
-	}
-	return s
-}
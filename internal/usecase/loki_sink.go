@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
+)
+
+// LokiSink writes items as one Loki stream each, with logfmt-style lines.
+type LokiSink struct {
+	loki LokiWriter
+}
+
+// NewLokiSink adapts a LokiWriter to the Sink port.
+func NewLokiSink(loki LokiWriter) *LokiSink {
+	return &LokiSink{loki: loki}
+}
+
+// Write implements Sink.
+func (s *LokiSink) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	_, span := tracing.Start(ctx, "loki.payload_to_streams", tracing.KindInternal, tracing.Int("items", len(items)))
+	streams := make([]domain.LokiStream, 0, len(items))
+	var (
+		meta *domain.Meta
+		base map[string]interface{}
+	)
+	for _, it := range items {
+		if it.Meta != meta || base == nil {
+			meta = it.Meta
+			base = meta.Fields()
+		}
+		fields := copyMap(base)
+		for k, v := range it.Fields {
+			fields[k] = v
+		}
+		streams = append(streams, toLokiStream(fields))
+	}
+	span.End()
+	return s.loki.Push(ctx, tenantID, streams)
+}
+
+func toLokiStream(fields map[string]interface{}) domain.LokiStream {
+	labels := map[string]string{
+		"app":         fmt.Sprint(fields["app"]),
+		"kind":        fmt.Sprint(fields["kind"]),
+		"level":       fmt.Sprint(fields["level"]),
+		"environment": fmt.Sprint(fields["environment"]),
+		"browser":     fmt.Sprint(fields["browser_name"]),
+		"session_id":  fmt.Sprint(fields["session_id"]),
+	}
+	line := formatLogLine(fields)
+	ts := fmt.Sprintf("%d", time.Now().UnixNano())
+	return domain.LokiStream{
+		Stream: labels,
+		Values: [][]string{{ts, line}},
+	}
+}
+
+func formatLogLine(fields map[string]interface{}) string {
+	var s string
+	for k, v := range fields {
+		switch val := v.(type) {
+		case string:
+			s += fmt.Sprintf("%s=%q ", k, val)
+		case bool, float64, int, int64:
+			s += fmt.Sprintf("%s=%v ", k, val)
+		default:
+			s += fmt.Sprintf("%s=%v ", k, v)
+		}
+	}
+	return s
+}
@@ -0,0 +1,346 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
+)
+
+// Reasons passed to Recorder.ItemsDropped by the SinkRouter.
+const (
+	DropReasonQueueFull = "queue_full"
+	DropReasonNoRoute   = "no_route"
+)
+
+// ErrQueueFull is returned by SinkRouter.Write when no target sink could take the items.
+var ErrQueueFull = errors.New("sink queues full")
+
+const (
+	defaultQueueSize    = 10000
+	defaultSinkTimeout  = 30 * time.Second
+	defaultQueueWorkers = 1
+)
+
+// Route sends the items it matches to Sinks. Empty match lists match anything.
+type Route struct {
+	Tenants      []string
+	Apps         []string
+	Environments []string
+	Kinds        []string
+	Sinks        []string
+}
+
+func (r Route) matches(tenantID string, it domain.Item) bool {
+	var app, env string
+	if it.Meta != nil {
+		app, env = it.Meta.App.Name, it.Meta.App.Environment
+	}
+	return matchAny(r.Tenants, tenantID) && matchAny(r.Apps, app) &&
+		matchAny(r.Environments, env) && matchAny(r.Kinds, it.Kind)
+}
+
+func matchAny(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if s == v || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueObserver receives the number of items waiting in each sink queue.
+type QueueObserver interface {
+	SetQueueDepth(sink string, n int)
+}
+
+// SinkRouter is a Sink that fans items out to named sinks by route. Each sink has its own
+// bounded queue and workers, so a slow or failing sink never blocks the request or the others.
+type SinkRouter struct {
+	routes   []Route
+	queues   map[string]*sinkQueue
+	order    []string
+	log      *slog.Logger
+	recorder Recorder
+	wg       sync.WaitGroup
+}
+
+// RouterOption configures the SinkRouter.
+type RouterOption func(*routerConfig)
+
+type routerConfig struct {
+	queueSize int
+	workers   int
+	timeout   time.Duration
+	log       *slog.Logger
+	recorder  Recorder
+	observer  QueueObserver
+}
+
+// WithQueueSize bounds each sink queue to n items (default 10000).
+func WithQueueSize(n int) RouterOption {
+	return func(c *routerConfig) {
+		c.queueSize = n
+	}
+}
+
+// WithSinkTimeout bounds each sink write (default 30s).
+func WithSinkTimeout(d time.Duration) RouterOption {
+	return func(c *routerConfig) {
+		c.timeout = d
+	}
+}
+
+// WithQueueWorkers sets the number of concurrent writers per sink (default 1).
+func WithQueueWorkers(n int) RouterOption {
+	return func(c *routerConfig) {
+		c.workers = n
+	}
+}
+
+// WithRouterLogger sets the logger for sink write failures.
+func WithRouterLogger(l *slog.Logger) RouterOption {
+	return func(c *routerConfig) {
+		c.log = l
+	}
+}
+
+// WithRouterRecorder reports items dropped by full queues, missing routes and sink errors.
+func WithRouterRecorder(r Recorder) RouterOption {
+	return func(c *routerConfig) {
+		c.recorder = r
+	}
+}
+
+// WithQueueObserver reports queue depth after every enqueue and write.
+func WithQueueObserver(o QueueObserver) RouterOption {
+	return func(c *routerConfig) {
+		c.observer = o
+	}
+}
+
+// NewSinkRouter starts the queue workers for sinks. Items go to the sinks of the first matching
+// route; with no routes every item goes to every sink. Call Close to drain the queues.
+func NewSinkRouter(sinks map[string]Sink, routes []Route, opts ...RouterOption) (*SinkRouter, error) {
+	c := routerConfig{queueSize: defaultQueueSize, workers: defaultQueueWorkers, timeout: defaultSinkTimeout}
+	for _, fn := range opts {
+		fn(&c)
+	}
+	if c.log == nil {
+		c.log = slog.Default()
+	}
+	if c.queueSize <= 0 || c.workers <= 0 {
+		return nil, errors.New("sink router: queue size and workers must be positive")
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("sink router: no sinks")
+	}
+	for _, rt := range routes {
+		for _, name := range rt.Sinks {
+			if _, ok := sinks[name]; !ok {
+				return nil, errors.New("sink router: route references unknown sink " + name)
+			}
+		}
+	}
+
+	r := &SinkRouter{routes: routes, queues: make(map[string]*sinkQueue), log: c.log, recorder: c.recorder}
+	for name, sink := range sinks {
+		q := &sinkQueue{
+			name:     name,
+			sink:     sink,
+			ch:       make(chan sinkBatch, c.queueSize),
+			capacity: int64(c.queueSize),
+			timeout:  c.timeout,
+			observer: c.observer,
+		}
+		r.queues[name] = q
+		r.order = append(r.order, name)
+		for i := 0; i < c.workers; i++ {
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				r.run(q)
+			}()
+		}
+	}
+	return r, nil
+}
+
+// Write implements Sink. It only enqueues; sink errors are logged and counted by the workers.
+// It fails only when none of the target queues accepted the items.
+func (r *SinkRouter) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	bySink := make(map[string][]domain.Item)
+	unrouted := 0
+	for _, it := range items {
+		targets := r.targets(tenantID, it)
+		if len(targets) == 0 {
+			unrouted++
+			continue
+		}
+		for _, name := range targets {
+			bySink[name] = append(bySink[name], it)
+		}
+	}
+	if unrouted > 0 {
+		r.dropped(tenantID, DropReasonNoRoute, unrouted)
+	}
+
+	accepted := len(bySink) == 0
+	for name, batch := range bySink {
+		if r.queues[name].enqueue(sinkBatch{tenant: tenantID, items: batch, span: tracing.SpanFromContext(ctx)}) {
+			accepted = true
+			continue
+		}
+		r.log.WarnContext(ctx, "sink queue full", "sink", name, "tenant", tenantID, "items", len(batch))
+		r.dropped(tenantID, DropReasonQueueFull, len(batch))
+	}
+	if !accepted {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Close stops accepting items and waits for the queues to drain or ctx to end.
+func (r *SinkRouter) Close(ctx context.Context) error {
+	for _, name := range r.order {
+		r.queues[name].close()
+	}
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// QueueDepth returns the number of items waiting for sink.
+func (r *SinkRouter) QueueDepth(sink string) int {
+	if q, ok := r.queues[sink]; ok {
+		return int(q.depth.Load())
+	}
+	return 0
+}
+
+// QueueCapacity returns the item capacity of each sink queue.
+func (r *SinkRouter) QueueCapacity() int {
+	for _, q := range r.queues {
+		return int(q.capacity)
+	}
+	return 0
+}
+
+// Sinks returns the names of the routed sinks.
+func (r *SinkRouter) Sinks() []string {
+	return append([]string(nil), r.order...)
+}
+
+func (r *SinkRouter) targets(tenantID string, it domain.Item) []string {
+	if len(r.routes) == 0 {
+		return r.order
+	}
+	for _, rt := range r.routes {
+		if rt.matches(tenantID, it) {
+			return rt.Sinks
+		}
+	}
+	return nil
+}
+
+func (r *SinkRouter) run(q *sinkQueue) {
+	for b := range q.ch {
+		ctx, cancel := context.WithTimeout(tracing.ContextWithSpan(context.Background(), b.span), q.timeout)
+		ctx, span := tracing.Start(ctx, "sink.queue_write", tracing.KindInternal,
+			tracing.String("sink", q.name), tracing.Int("items", len(b.items)))
+		err := q.sink.Write(ctx, b.tenant, b.items)
+		span.RecordError(err)
+		span.End()
+		cancel()
+		q.done(len(b.items))
+		if err != nil {
+			r.log.Error("sink write failed", "sink", q.name, "tenant", b.tenant, "items", len(b.items), "error", err)
+			r.dropped(b.tenant, DropReasonSinkError, len(b.items))
+		}
+	}
+}
+
+func (r *SinkRouter) dropped(tenantID, reason string, n int) {
+	if r.recorder != nil {
+		r.recorder.ItemsDropped(tenantID, reason, n)
+	}
+}
+
+type sinkBatch struct {
+	tenant string
+	items  []domain.Item
+	// span is the request's span, so the queued write joins its trace.
+	span *tracing.Span
+}
+
+// sinkQueue bounds pending work by item count, not by batch count.
+type sinkQueue struct {
+	name     string
+	sink     Sink
+	ch       chan sinkBatch
+	capacity int64
+	depth    atomic.Int64
+	timeout  time.Duration
+	observer QueueObserver
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (q *sinkQueue) enqueue(b sinkBatch) bool {
+	n := int64(len(b.items))
+	if q.depth.Add(n) > q.capacity {
+		q.depth.Add(-n)
+		return false
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.depth.Add(-n)
+		return false
+	}
+	select {
+	case q.ch <- b:
+		q.observe()
+		return true
+	default:
+		q.depth.Add(-n)
+		return false
+	}
+}
+
+func (q *sinkQueue) done(n int) {
+	q.depth.Add(-int64(n))
+	q.observe()
+}
+
+func (q *sinkQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}
+
+func (q *sinkQueue) observe() {
+	if q.observer != nil {
+		q.observer.SetQueueDepth(q.name, int(q.depth.Load()))
+	}
+}
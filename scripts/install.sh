@@ -196,7 +196,7 @@ get_user_input() {
     ALLOW_ORIGINS=${ALLOW_ORIGINS:-"*"}
     PORT=${PORT:-3000}
     JWT_ISSUER=${JWT_ISSUER:-"trusted-issuer"}
-    JWT_VALIDATE_EXP=${JWT_VALIDATE_EXP:-"false"}
+    JWT_VALIDATE_EXP=${JWT_VALIDATE_EXP:-"true"}
     INSTALL_CADDY=${INSTALL_CADDY:-"false"}
 
     # 2. Check if we have required variables for auto-install
@@ -398,7 +398,7 @@ write_env_file() {
     chmod 755 "$CONFIG_DIR"
     PORT=${PORT:-3000}
     JWT_ISSUER=${JWT_ISSUER:-trusted-issuer}
-    JWT_VALIDATE_EXP=${JWT_VALIDATE_EXP:-false}
+    JWT_VALIDATE_EXP=${JWT_VALIDATE_EXP:-true}
     cat > "$ENV_FILE" << EOF
 # Faro Collector - generated by install.sh
 SECRET_KEY=$SECRET_KEY
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndValidate(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.JWTBindClaims = true
+	validator := auth.NewValidator(cfg, nil)
+
+	token, err := auth.Issue(cfg.SecretKey, auth.IssueParams{
+		Tenant:  "elven",
+		Role:    "user",
+		Issuer:  cfg.JWTIssuer,
+		TTL:     time.Hour,
+		Origins: []string{"https://*.elven.works"},
+	}, time.Now())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		req            auth.Request
+		expectedReason auth.Reason
+	}{
+		{name: "Bound tenant and origin", req: auth.Request{Tenant: "elven", Origin: "https://app.elven.works"}},
+		{name: "Other tenant", req: auth.Request{Tenant: "acme", Origin: "https://app.elven.works"}, expectedReason: auth.ReasonTenantMismatch},
+		{name: "Other origin", req: auth.Request{Tenant: "elven", Origin: "https://evil.example"}, expectedReason: auth.ReasonOriginMismatch},
+		{name: "No origin header", req: auth.Request{Tenant: "elven"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := validator.Validate(token, tt.req)
+			if tt.expectedReason == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "elven", claims[auth.ClaimTenant])
+				assert.NotEmpty(t, claims["jti"])
+				return
+			}
+			var authErr *auth.Error
+			require.True(t, errors.As(err, &authErr))
+			assert.Equal(t, tt.expectedReason, authErr.Reason)
+			assert.True(t, authErr.Forbidden())
+		})
+	}
+}
+
+func TestValidateExplainsFailure(t *testing.T) {
+	cfg := testConfig(t)
+	validator := auth.NewValidator(cfg, nil)
+
+	expired, err := auth.Issue(cfg.SecretKey, auth.IssueParams{Role: "user", Issuer: cfg.JWTIssuer, TTL: time.Hour}, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	wrongIssuer, err := auth.Issue(cfg.SecretKey, auth.IssueParams{Role: "admin", Issuer: "elsewhere"}, time.Now())
+	require.NoError(t, err)
+	otherKey, err := auth.Issue("a-different-key-that-is-also-long-enough-to-pass-the-length-check-ok", auth.IssueParams{Role: "user", Issuer: cfg.JWTIssuer}, time.Now())
+	require.NoError(t, err)
+
+	for token, reason := range map[string]auth.Reason{
+		"":          auth.ReasonMissing,
+		expired:     auth.ReasonExpired,
+		wrongIssuer: auth.ReasonInvalidIssuer,
+		otherKey:    auth.ReasonInvalid,
+	} {
+		_, err := validator.Validate(token, auth.Request{})
+		var authErr *auth.Error
+		require.True(t, errors.As(err, &authErr))
+		assert.Equal(t, reason, authErr.Reason)
+	}
+}
+
+func TestBindingIsOptIn(t *testing.T) {
+	cfg := testConfig(t)
+	token, err := auth.Issue(cfg.SecretKey, auth.IssueParams{
+		Tenant:  "elven",
+		Role:    "user",
+		Issuer:  cfg.JWTIssuer,
+		Origins: []string{"https://app.elven.works"},
+	}, time.Now())
+	require.NoError(t, err)
+
+	// Tokens minted before binding existed may carry a tenant claim; they keep working until it is enabled.
+	_, err = auth.NewValidator(cfg, nil).Validate(token, auth.Request{Tenant: "acme", Origin: "https://evil.example"})
+	assert.NoError(t, err)
+
+	cfg.JWTBindClaims = true
+	_, err = auth.NewValidator(cfg, nil).Validate(token, auth.Request{Tenant: "acme", Origin: "https://app.elven.works"})
+	var authErr *auth.Error
+	require.True(t, errors.As(err, &authErr))
+	assert.Equal(t, auth.ReasonTenantMismatch, authErr.Reason)
+}
+
+func TestIssueRejectsUnknownRole(t *testing.T) {
+	cfg := testConfig(t)
+	_, err := auth.Issue(cfg.SecretKey, auth.IssueParams{Role: "superuser", Issuer: cfg.JWTIssuer}, time.Now())
+	assert.Error(t, err)
+
+	token, err := auth.Issue(cfg.SecretKey, auth.IssueParams{Role: " Admin ", Issuer: cfg.JWTIssuer}, time.Now())
+	require.NoError(t, err)
+	_, err = auth.NewValidator(cfg, nil).Validate(token, auth.Request{})
+	assert.NoError(t, err)
+}
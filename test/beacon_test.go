@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingLoki counts the pushes it receives.
+type countingLoki struct {
+	mu     sync.Mutex
+	pushes int
+}
+
+func (l *countingLoki) Push(_ context.Context, _ string, _ []domain.LokiStream) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pushes++
+	return nil
+}
+
+func TestBeacon(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedPushes int
+	}{
+		{name: "Query token", path: "/beacon/elven?token=" + token, body: minimalCollectPayload, expectedPushes: 1},
+		{name: "Path token", path: "/beacon/elven/" + token, body: minimalCollectPayload, expectedPushes: 1},
+		{name: "Invalid token", path: "/beacon/elven?token=nope", body: minimalCollectPayload},
+		{name: "Missing token", path: "/beacon/elven", body: minimalCollectPayload},
+		{name: "Invalid JSON", path: "/beacon/elven?token=" + token, body: "{"},
+		{name: "Empty payload", path: "/beacon/elven?token=" + token, body: `{"meta":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loki := &countingLoki{}
+			router := httpadapter.Router(testConfig(t), usecase.NewCollectorService(loki, nil))
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+			req.Header.Set("Origin", "http://localhost")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, tt.expectedPushes, loki.pushes)
+		})
+	}
+}
+
+func TestCollectTextPlain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := httpadapter.Router(testConfig(t), usecase.NewCollectorService(noopLoki{}, nil))
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	req := httptest.NewRequest(http.MethodPost, "/collect/elven/"+token, strings.NewReader(minimalCollectPayload))
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// ?token= is only honoured on beacon routes.
+	req = httptest.NewRequest(http.MethodPost, "/collect/elven?token="+token, strings.NewReader(minimalCollectPayload))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestBeaconCrossOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+	cfg := testConfig(t)
+	cfg.AllowOrigins = []string{"https://shop.example"}
+
+	send := func(origin string) (*httptest.ResponseRecorder, int) {
+		loki := &countingLoki{}
+		router := httpadapter.Router(cfg, usecase.NewCollectorService(loki, nil))
+		req := httptest.NewRequest(http.MethodPost, "/beacon/elven?token="+token, strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w, loki.pushes
+	}
+
+	// No preflight: the simple request itself carries the origin and credentials.
+	w, pushes := send("https://shop.example")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 1, pushes)
+	assert.Equal(t, "https://shop.example", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w, pushes = send("https://evil.example")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Zero(t, pushes)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestBeaconQueryTokenIsGatedSeparately(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	tests := []struct {
+		name             string
+		allowPathToken   bool
+		beaconQueryToken bool
+		expectedPushes   int
+	}{
+		{name: "Query token without path tokens", beaconQueryToken: true, expectedPushes: 1},
+		{name: "Query token disabled", allowPathToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.AllowPathToken = tt.allowPathToken
+			cfg.BeaconQueryToken = tt.beaconQueryToken
+			loki := &countingLoki{}
+			router := httpadapter.Router(cfg, usecase.NewCollectorService(loki, nil))
+			req := httptest.NewRequest(http.MethodPost, "/beacon/elven?token="+token, strings.NewReader(minimalCollectPayload))
+			req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, tt.expectedPushes, loki.pushes)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/clickhouse"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClickHouse records statements and JSONEachRow inserts by query.
+type fakeClickHouse struct {
+	mu         sync.Mutex
+	statements []string
+	rows       map[string][]map[string]interface{}
+	async      []string
+}
+
+func (f *fakeClickHouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-ClickHouse-User") != "faro" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		b, _ := io.ReadAll(r.Body)
+		f.statements = append(f.statements, string(b))
+		return
+	}
+	f.async = append(f.async, r.URL.Query().Get("async_insert")+"/"+r.URL.Query().Get("wait_for_async_insert"))
+	if f.rows == nil {
+		f.rows = make(map[string][]map[string]interface{})
+	}
+	sc := bufio.NewScanner(r.Body)
+	for sc.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.rows[query] = append(f.rows[query], row)
+	}
+}
+
+func TestClickHouseSinkInsertsTypedRows(t *testing.T) {
+	fake := &fakeClickHouse{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := clickhouse.NewClient(clickhouse.Options{URL: srv.URL, Database: "rum", Username: "faro", Password: "secret", Timeout: time.Second})
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(client))
+
+	p := otlpTestPayload()
+	p.Meta.View.Name = "/checkout"
+	p.Meta.Extra = map[string]interface{}{"k6": map[string]interface{}{"isK6Browser": true}}
+	p.Measurements = []domain.Measurement{
+		{Type: "web-vitals", Values: map[string]float64{"lcp": 2400.5, "cls": 0.02}, Timestamp: "2026-01-02T03:04:05Z"},
+		{Type: "web-vitals", Values: map[string]float64{"lcp": 900}},
+	}
+	p.Events = []domain.Event{{Name: "click", Domain: "ui", Attributes: map[string]interface{}{"target": "buy"}}}
+	require.NoError(t, svc.Collect(context.Background(), "elven", p))
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Len(t, fake.rows, 4, "one insert per table")
+	assert.Equal(t, []string{"1/1", "1/1", "1/1", "1/1"}, fake.async, "inserts are async and wait for the flush")
+
+	measurements := fake.rows["INSERT INTO rum.faro_measurements FORMAT JSONEachRow"]
+	require.Len(t, measurements, 2)
+	m := measurements[0]
+	assert.Equal(t, "2026-01-02 03:04:05.000", m["timestamp"])
+	assert.Equal(t, "elven", m["tenant"])
+	assert.Equal(t, "shop", m["app"])
+	assert.Equal(t, "/checkout", m["view_name"])
+	assert.Equal(t, true, m["browser_mobile"])
+	assert.Equal(t, "s-1", m["session_id"])
+	assert.Equal(t, "web-vitals", m["type"])
+	assert.Equal(t, map[string]interface{}{"lcp": 2400.5, "cls": 0.02}, m["values"])
+	assert.Equal(t, map[string]interface{}{"k6_isK6Browser": "true"}, m["meta"])
+
+	events := fake.rows["INSERT INTO rum.faro_events FORMAT JSONEachRow"]
+	require.Len(t, events, 1)
+	assert.Equal(t, "click", events[0]["name"])
+	assert.Equal(t, map[string]interface{}{"target": "buy"}, events[0]["attributes"])
+
+	exceptions := fake.rows["INSERT INTO rum.faro_exceptions FORMAT JSONEachRow"]
+	require.Len(t, exceptions, 1)
+	assert.Equal(t, "TypeError", exceptions[0]["type"])
+	assert.Equal(t, "x is undefined", exceptions[0]["value"])
+
+	logs := fake.rows["INSERT INTO rum.faro_logs FORMAT JSONEachRow"]
+	require.Len(t, logs, 1)
+	assert.Equal(t, "warn", logs[0]["level"])
+	assert.Equal(t, "hello", logs[0]["message"])
+}
+
+func TestClickHouseSchema(t *testing.T) {
+	stmts := clickhouse.Schema("rum")
+	require.Len(t, stmts, 5)
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS rum", stmts[0])
+	for _, table := range []string{"faro_logs", "faro_events", "faro_measurements", "faro_exceptions"} {
+		found := false
+		for _, s := range stmts {
+			if strings.Contains(s, "CREATE TABLE IF NOT EXISTS rum."+table) {
+				found = true
+			}
+		}
+		assert.True(t, found, table)
+	}
+	for _, s := range stmts[1:] {
+		assert.Contains(t, s, "PARTITION BY toYYYYMM(received_at)", "partitions must not follow browser clocks")
+	}
+
+	fake := &fakeClickHouse{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := clickhouse.NewClient(clickhouse.Options{URL: srv.URL, Database: "rum", Username: "faro", Password: "secret"})
+	require.NoError(t, client.EnsureSchema(context.Background()))
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Equal(t, stmts, fake.statements)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCompressedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.MaxDecompressedBytes = 64 << 10
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	bomb := `{"meta":{},"logs":[{"message":"` + strings.Repeat("a", 1<<20) + `"}]}`
+
+	tests := []struct {
+		name           string
+		header         string
+		body           []byte
+		expectedStatus int
+	}{
+		{name: "gzip", header: "gzip", body: compress(t, "gzip", []byte(minimalCollectPayload)), expectedStatus: 200},
+		{name: "deflate", header: "deflate", body: compress(t, "deflate", []byte(minimalCollectPayload)), expectedStatus: 200},
+		{name: "raw deflate", header: "deflate", body: compress(t, "raw-deflate", []byte(minimalCollectPayload)), expectedStatus: 200},
+		{name: "brotli", header: "br", body: compress(t, "br", []byte(minimalCollectPayload)), expectedStatus: 200},
+		{name: "Identity", header: "identity", body: []byte(minimalCollectPayload), expectedStatus: 200},
+		{name: "Unsupported", header: "zstd", body: []byte(minimalCollectPayload), expectedStatus: 415},
+		{name: "Corrupt gzip", header: "gzip", body: []byte("not gzip at all"), expectedStatus: 400},
+		{name: "Zip bomb", header: "gzip", body: compress(t, "gzip", []byte(bomb)), expectedStatus: 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/collect/elven/"+token, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tt.header)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
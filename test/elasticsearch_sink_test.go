@@ -0,0 +1,181 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/elasticsearch"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkDoc struct {
+	Index   string
+	Message string
+}
+
+// fakeBulk answers _bulk with the status chosen by statusFor for each document.
+type fakeBulk struct {
+	mu        sync.Mutex
+	requests  [][]bulkDoc
+	statusFor func(attempt int, d bulkDoc) int
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if user, pass, _ := r.BasicAuth(); user != "faro" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var docs []bulkDoc
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		var action map[string]map[string]string
+		_ = json.Unmarshal(sc.Bytes(), &action)
+		sc.Scan()
+		var doc struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(sc.Bytes(), &doc)
+		docs = append(docs, bulkDoc{Index: action["create"]["_index"], Message: doc.Message})
+	}
+
+	f.mu.Lock()
+	attempt := len(f.requests)
+	f.requests = append(f.requests, docs)
+	f.mu.Unlock()
+
+	var items []string
+	hasErrors := false
+	for _, d := range docs {
+		status := f.statusFor(attempt, d)
+		if status >= 300 {
+			hasErrors = true
+			items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"x","reason":"%s"}}}`, status, d.Message))
+		} else {
+			items = append(items, fmt.Sprintf(`{"create":{"status":%d}}`, status))
+		}
+	}
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+}
+
+func esItems() []domain.Item {
+	meta := &domain.Meta{App: domain.AppMeta{Name: "Shop App", Environment: "prod"}}
+	mk := func(msg string) domain.Item {
+		return domain.Item{Kind: domain.KindLog, Timestamp: "2026-03-04T05:06:07Z", Meta: meta, Fields: map[string]interface{}{"message": msg, "level": "info"}}
+	}
+	return []domain.Item{mk("ok"), mk("throttled"), mk("bad mapping")}
+}
+
+func TestElasticsearchPartialFailures(t *testing.T) {
+	fake := &fakeBulk{statusFor: func(attempt int, d bulkDoc) int {
+		switch {
+		case d.Message == "throttled" && attempt == 0:
+			return http.StatusTooManyRequests
+		case d.Message == "bad mapping":
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := elasticsearch.NewClient(elasticsearch.Options{
+		URL: srv.URL, Username: "faro", Password: "secret",
+		Index: "faro-{tenant}-{app}-{kind}-{date}", Apps: []string{"Shop App"}, Timeout: time.Second, MaxRetries: 2,
+	})
+	err := client.Write(context.Background(), "Elven", esItems())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.ErrorIs(t, err, domain.ErrSinkRejected, "a rejected document must not trip the circuit breaker")
+	assert.NotContains(t, err.Error(), "throttled")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Len(t, fake.requests, 2)
+	assert.Len(t, fake.requests[0], 3)
+	require.Len(t, fake.requests[1], 1, "only the throttled item is retried")
+	assert.Equal(t, "throttled", fake.requests[1][0].Message)
+	// The date is when the collector received the items, not the client's 2026-03-04.
+	assert.Equal(t, "faro-elven-shop_app-log-"+time.Now().UTC().Format("2006.01.02"), fake.requests[0][0].Index)
+}
+
+func TestElasticsearchAllowlistsClientIndexParts(t *testing.T) {
+	fake := &fakeBulk{statusFor: func(int, bulkDoc) int { return http.StatusCreated }}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	long := strings.Repeat("a", 300)
+	client := elasticsearch.NewClient(elasticsearch.Options{
+		URL: srv.URL, Username: "faro", Password: "secret", Index: "faro-{app}-{env}",
+		Apps: []string{"shop", "-_+Hidden", long}, Envs: []string{"prod", "pro.d"},
+	})
+
+	tests := []struct {
+		app, env string
+		expected string
+	}{
+		{app: "shop", env: "prod", expected: "faro-shop-prod"},
+		{app: "evil-app-creating-indices", env: "staging-123", expected: "faro-other-other"},
+		{app: "..", env: ".", expected: "faro-other-other"},
+		{app: "-_+Hidden", env: "pro.d", expected: "faro-hidden-pro_d"},
+		{app: long, env: "prod", expected: "faro-" + strings.Repeat("a", 64) + "-prod"},
+	}
+	for _, tt := range tests {
+		meta := &domain.Meta{App: domain.AppMeta{Name: tt.app, Environment: tt.env}}
+		require.NoError(t, client.Write(context.Background(), "elven", []domain.Item{{Kind: domain.KindLog, Meta: meta}}))
+		fake.mu.Lock()
+		got := fake.requests[len(fake.requests)-1][0].Index
+		fake.mu.Unlock()
+		assert.Equal(t, tt.expected, got)
+	}
+}
+
+func TestElasticsearchIndexRequiresAllowlists(t *testing.T) {
+	t.Setenv("SINK", "elasticsearch")
+	t.Setenv("ELASTICSEARCH_URL", "http://localhost:9200")
+	t.Setenv("ELASTICSEARCH_INDEX", "faro-{app}-{env}-{date}")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrElasticsearchAllowlist)
+
+	t.Setenv("ELASTICSEARCH_INDEX_APPS", "shop,admin")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrElasticsearchAllowlist)
+
+	t.Setenv("ELASTICSEARCH_INDEX_ENVS", "prod,staging")
+	assert.NoError(t, config.Load().Validate())
+}
+
+func TestElasticsearchGivesUpAfterRetries(t *testing.T) {
+	fake := &fakeBulk{statusFor: func(_ int, d bulkDoc) int {
+		if d.Message == "bad mapping" {
+			return http.StatusBadRequest
+		}
+		return http.StatusServiceUnavailable
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := elasticsearch.NewClient(elasticsearch.Options{URL: srv.URL, Username: "faro", Password: "secret", MaxRetries: 1})
+	err := client.Write(context.Background(), "elven", esItems()[1:])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not indexed after 1 retries")
+	assert.NotErrorIs(t, err, domain.ErrSinkRejected, "items that were never indexed must still count as a sink failure")
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Len(t, fake.requests, 2)
+}
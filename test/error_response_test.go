@@ -0,0 +1,82 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+
+	tests := []struct {
+		name           string
+		token          string
+		requestID      string
+		expectedStatus int
+		expectedCode   httpadapter.ErrorCode
+	}{
+		{
+			name:           "Wrong issuer is not echoed",
+			token:          generateJWT(jwt.MapClaims{"role": "admin", "iss": "someone-else"}),
+			expectedStatus: 403,
+			expectedCode:   httpadapter.CodeForbidden,
+		},
+		{
+			name:           "Parser error is not echoed",
+			token:          "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.invalid.payload.signature",
+			expectedStatus: 401,
+			expectedCode:   httpadapter.CodeTokenInvalid,
+		},
+		{
+			name:           "Incoming request ID is kept",
+			token:          generateJWT(jwt.MapClaims{"iss": "trusted-issuer"}),
+			requestID:      "edge-1234",
+			expectedStatus: 403,
+			expectedCode:   httpadapter.CodeForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/collect/elven/"+tt.token, strings.NewReader(minimalCollectPayload))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var raw map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+			assert.ElementsMatch(t, []string{"error", "code", "request_id"}, keys(raw))
+			assert.Equal(t, string(tt.expectedCode), raw["code"])
+			assert.Equal(t, w.Header().Get("X-Request-ID"), raw["request_id"])
+			if tt.requestID != "" {
+				assert.Equal(t, tt.requestID, raw["request_id"])
+			}
+			assert.NotContains(t, w.Body.String(), "trusted-issuer")
+		})
+	}
+}
+
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
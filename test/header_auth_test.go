@@ -0,0 +1,85 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectHeaderToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer", "exp": time.Now().Add(1 * time.Hour).Unix()})
+
+	tests := []struct {
+		name           string
+		allowPath      bool
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "Bearer header",
+			allowPath:      true,
+			path:           "/collect/elven",
+			headers:        map[string]string{"Authorization": "Bearer " + token},
+			expectedStatus: 200,
+		},
+		{
+			name:           "Faro API key header",
+			allowPath:      true,
+			path:           "/collect/elven",
+			headers:        map[string]string{"X-Faro-Api-Key": token},
+			expectedStatus: 200,
+		},
+		{
+			name:           "No header",
+			allowPath:      true,
+			path:           "/collect/elven",
+			expectedStatus: 401,
+		},
+		{
+			name:           "Path token still accepted",
+			allowPath:      true,
+			path:           "/collect/elven/" + token,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Path token disabled",
+			allowPath:      false,
+			path:           "/collect/elven/" + token,
+			expectedStatus: 404,
+		},
+		{
+			name:           "Header works with path token disabled",
+			allowPath:      false,
+			path:           "/collect/elven",
+			headers:        map[string]string{"Authorization": "Bearer " + token},
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.AllowPathToken = tt.allowPath
+			router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(minimalCollectPayload))
+			req.Header.Set("Content-Type", "application/json")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
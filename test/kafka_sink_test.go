@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/kafka"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func kafkaCluster(t *testing.T, topics ...string) []string {
+	t.Helper()
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topics...))
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster.ListenAddrs()
+}
+
+// consumeKafka reads records from the topics until want records arrive or the deadline passes.
+func consumeKafka(t *testing.T, brokers []string, want int, topics ...string) []*kgo.Record {
+	t.Helper()
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) < want {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		require.Empty(t, fetches.Errors())
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func kafkaPayload(session string) *domain.Payload {
+	p := &domain.Payload{
+		Logs:   []domain.LogEntry{{Message: "first", Level: "info"}, {Message: "second", Level: "warn"}},
+		Events: []domain.Event{{Name: "click"}},
+	}
+	p.Meta.App = domain.AppMeta{Name: "shop", Environment: "prod"}
+	p.Meta.Session.ID = session
+	return p
+}
+
+func header(r *kgo.Record, key string) string {
+	for _, h := range r.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestKafkaProducerPerItem(t *testing.T) {
+	brokers := kafkaCluster(t, "elven.log", "elven.event")
+	producer, err := kafka.NewProducer(kafka.Options{Brokers: brokers, Topic: "{tenant}.{kind}", Compression: "snappy"})
+	require.NoError(t, err)
+	defer producer.Close(context.Background())
+
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(producer))
+	require.NoError(t, svc.Collect(context.Background(), "elven", kafkaPayload("sess-1")))
+
+	records := consumeKafka(t, brokers, 3, "elven.log", "elven.event")
+	require.Len(t, records, 3)
+
+	var logs []string
+	for _, r := range records {
+		assert.Equal(t, "sess-1", string(r.Key))
+		assert.Equal(t, "elven", header(r, "tenant"))
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(r.Value, &msg))
+		assert.Equal(t, header(r, "kind"), msg["kind"])
+		switch r.Topic {
+		case "elven.log":
+			logs = append(logs, msg["message"].(string))
+			// Same session key means same partition, so order is preserved.
+			assert.Equal(t, records[0].Partition, r.Partition)
+		case "elven.event":
+			assert.Equal(t, "event", msg["kind"])
+			fields := msg["fields"].(map[string]interface{})
+			assert.Equal(t, "shop", fields["app"])
+		}
+	}
+	assert.Equal(t, []string{"first", "second"}, logs)
+}
+
+func TestKafkaProducerBatch(t *testing.T) {
+	brokers := kafkaCluster(t, "faro-log", "faro-event")
+	producer, err := kafka.NewProducer(kafka.Options{Brokers: brokers, Batch: true, Acks: "leader"})
+	require.NoError(t, err)
+	defer producer.Close(context.Background())
+
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(producer))
+	require.NoError(t, svc.Collect(context.Background(), "elven", kafkaPayload("")))
+
+	records := consumeKafka(t, brokers, 2, "faro-log", "faro-event")
+	require.Len(t, records, 2)
+	for _, r := range records {
+		assert.Nil(t, r.Key, "items without session are unkeyed")
+		var batch []map[string]interface{}
+		require.NoError(t, json.Unmarshal(r.Value, &batch))
+		switch r.Topic {
+		case "faro-log":
+			require.Len(t, batch, 2)
+			assert.Equal(t, "first", batch[0]["message"])
+			assert.Equal(t, "second", batch[1]["message"])
+		case "faro-event":
+			require.Len(t, batch, 1)
+		}
+	}
+}
+
+func TestKafkaProducerRejectsUnknownOptions(t *testing.T) {
+	_, err := kafka.NewProducer(kafka.Options{})
+	assert.Error(t, err)
+	_, err = kafka.NewProducer(kafka.Options{Brokers: []string{"localhost:9092"}, Compression: "brotli"})
+	assert.Error(t, err)
+	_, err = kafka.NewProducer(kafka.Options{Brokers: []string{"localhost:9092"}, Acks: "two"})
+	assert.Error(t, err)
+	_, err = kafka.NewProducer(kafka.Options{Brokers: []string{"localhost:9092"}, Topic: "faro-{app}"})
+	assert.Error(t, err, "{app} comes from the browser and needs an allowlist")
+}
+
+func TestKafkaProducerTopicAppAllowlist(t *testing.T) {
+	brokers := kafkaCluster(t, "faro-shop", "faro-other")
+	producer, err := kafka.NewProducer(kafka.Options{Brokers: brokers, Topic: "faro-{app}", Apps: []string{"shop"}})
+	require.NoError(t, err)
+	defer producer.Close(context.Background())
+
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(producer))
+	require.NoError(t, svc.Collect(context.Background(), "elven", kafkaPayload("sess-1")))
+	evil := kafkaPayload("sess-2")
+	evil.Meta.App.Name = "evil-app-creating-topics"
+	require.NoError(t, svc.Collect(context.Background(), "elven", evil))
+
+	topics := map[string]int{}
+	for _, r := range consumeKafka(t, brokers, 6, "faro-shop", "faro-other") {
+		topics[r.Topic]++
+	}
+	assert.Equal(t, map[string]int{"faro-shop": 3, "faro-other": 3}, topics)
+}
+
+func TestKafkaTopicAppRequiresAllowlist(t *testing.T) {
+	t.Setenv("SINK", "kafka")
+	t.Setenv("KAFKA_BROKERS", "localhost:9092")
+	t.Setenv("KAFKA_TOPIC", "faro-{app}")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrKafkaTopicApps)
+
+	t.Setenv("KAFKA_TOPIC_APPS", "shop,admin")
+	assert.NoError(t, config.Load().Validate())
+}
+
+func TestKafkaProducerRejectsLongTopic(t *testing.T) {
+	brokers := kafkaCluster(t)
+	producer, err := kafka.NewProducer(kafka.Options{Brokers: brokers, Topic: "{tenant}-{kind}"})
+	require.NoError(t, err)
+	defer producer.Close(context.Background())
+
+	tenant := strings.Repeat("t", 250)
+	err = producer.Write(context.Background(), tenant, []domain.Item{{Kind: domain.KindLog}})
+	assert.ErrorContains(t, err, "invalid topic name")
+}
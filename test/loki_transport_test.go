@@ -0,0 +1,198 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/loki"
+	"collector-fe-instrumentation/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM cert and key signed by the CA; dnsName makes it a server certificate.
+func (ca *testCA) issue(t *testing.T, cn, dnsName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if dnsName != "" {
+		tmpl.DNSNames = []string{dnsName}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// mtlsLoki starts a Loki stub that requires a client certificate from ca and records its CN.
+func mtlsLoki(t *testing.T, ca *testCA, serverName string) (*httptest.Server, func() []string) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "loki", serverName)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	var mu sync.Mutex
+	var clients []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		clients = append(clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{pair}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), clients...)
+	}
+}
+
+func writePEM(t *testing.T, dir, name string, data []byte, mod time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, mod, mod))
+	return path
+}
+
+var testStreams = []domain.LokiStream{{Stream: map[string]string{"app": "t"}, Values: [][]string{{"1", "x"}}}}
+
+func TestLokiTransportMTLSAndRotation(t *testing.T) {
+	ca := newTestCA(t)
+	srv, clients := mtlsLoki(t, ca, "loki.internal")
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	caFile := writePEM(t, dir, "ca.pem", ca.pem, start)
+	certPEM, keyPEM := ca.issue(t, "collector-v1", "")
+	certFile := writePEM(t, dir, "client.pem", certPEM, start)
+	keyFile := writePEM(t, dir, "client-key.pem", keyPEM, start)
+
+	transport, err := loki.NewTransport(loki.TransportOptions{
+		CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "loki.internal", MinVersion: "1.3",
+	})
+	require.NoError(t, err)
+	client := loki.New(loki.Options{URL: srv.URL, Transport: transport})
+	require.NoError(t, client.Push(context.Background(), "elven", testStreams))
+
+	certPEM, keyPEM = ca.issue(t, "collector-v2", "")
+	writePEM(t, dir, "client.pem", certPEM, start.Add(30*time.Second))
+	writePEM(t, dir, "client-key.pem", keyPEM, start.Add(30*time.Second))
+	transport.CloseIdleConnections()
+	require.NoError(t, client.Push(context.Background(), "elven", testStreams))
+
+	assert.Equal(t, []string{"collector-v1", "collector-v2"}, clients())
+}
+
+func TestLokiTransportRequiresClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	srv, _ := mtlsLoki(t, ca, "loki.internal")
+	caFile := writePEM(t, t.TempDir(), "ca.pem", ca.pem, time.Now())
+
+	transport, err := loki.NewTransport(loki.TransportOptions{CAFile: caFile, ServerName: "loki.internal"})
+	require.NoError(t, err)
+	err = loki.New(loki.Options{URL: srv.URL, Transport: transport}).Push(context.Background(), "elven", testStreams)
+	assert.Error(t, err)
+
+	// Without the CA the server certificate is not trusted.
+	transport, err = loki.NewTransport(loki.TransportOptions{ServerName: "loki.internal"})
+	require.NoError(t, err)
+	err = loki.New(loki.Options{URL: srv.URL, Transport: transport}).Push(context.Background(), "elven", testStreams)
+	assert.Error(t, err)
+}
+
+func TestLokiTransportProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	// The target is never contacted directly; only the proxy answers.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target := "http://" + ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	transport, err := loki.NewTransport(loki.TransportOptions{ProxyURL: proxy.URL, MaxIdleConns: 5, MaxIdleConnsPerHost: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 5, transport.MaxIdleConns)
+	assert.Equal(t, 2, transport.MaxIdleConnsPerHost)
+	require.NoError(t, loki.New(loki.Options{URL: target, Transport: transport}).Push(context.Background(), "elven", testStreams))
+	assert.Equal(t, []string{target + "/loki/api/v1/push"}, proxied)
+}
+
+func TestLokiTransportInvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := writePEM(t, dir, "bad.pem", []byte("not a certificate"), time.Now())
+	for name, opts := range map[string]loki.TransportOptions{
+		"missing ca":   {CAFile: filepath.Join(dir, "missing.pem")},
+		"empty ca":     {CAFile: notPEM},
+		"cert no key":  {CertFile: notPEM},
+		"bad key pair": {CertFile: notPEM, KeyFile: notPEM},
+		"tls version":  {MinVersion: "1.4"},
+		"proxy":        {ProxyURL: "://nope"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loki.NewTransport(opts)
+			assert.Error(t, err)
+		})
+	}
+}
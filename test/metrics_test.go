@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/metrics"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingLoki is a LokiWriter that always fails.
+type failingLoki struct{}
+
+func (failingLoki) Push(_ context.Context, _ string, _ []domain.LokiStream) error {
+	return errors.New("loki down")
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+	svc := usecase.NewCollectorService(m.InstrumentLoki(noopLoki{}), nil, usecase.WithRecorder(m))
+	router := httpadapter.Router(testConfig(t), svc, httpadapter.WithMetrics(m))
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	post := func(path string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(minimalCollectPayload))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	post("/collect/elven/" + token)
+	post("/collect/elven/" + token)
+	post("/collect/elven/not-a-token")
+
+	failing := usecase.NewCollectorService(m.InstrumentLoki(failingLoki{}), nil, usecase.WithRecorder(m))
+	// Three logs share one Loki stream; every item counts as dropped.
+	_ = failing.Collect(context.Background(), "acme", &domain.Payload{Logs: []domain.LogEntry{{Message: "x"}, {Message: "y"}, {Message: "z"}}})
+
+	// /metrics is served on the metrics listener, not the collect router.
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`faro_collector_http_requests_total{method="POST",route="/collect/:tenant/:token",status="200"} 2`,
+		`faro_collector_http_requests_total{method="POST",route="/collect/:tenant/:token",status="401"} 1`,
+		`faro_collector_items_received_total{kind="log",tenant="elven"} 2`,
+		`faro_collector_auth_failures_total{reason="token_invalid"} 1`,
+		`faro_collector_loki_push_errors_total{tenant="acme"} 1`,
+		`faro_collector_items_dropped_total{reason="sink_error",tenant="acme"} 3`,
+		`faro_collector_loki_push_duration_seconds_count{outcome="success"} 2`,
+		`faro_collector_http_request_body_bytes_count{route="/collect/:tenant/:token"} 2`,
+	} {
+		assert.Contains(t, body, want)
+	}
+}
+
+func TestMetricsTenantLabelIsBounded(t *testing.T) {
+	scrape := func(m *metrics.Metrics) string {
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return w.Body.String()
+	}
+
+	capped := metrics.New(metrics.WithMaxTenants(2))
+	for _, tenant := range []string{"a", "b", "c", "d", "a"} {
+		capped.ItemsReceived(tenant, "log", 1)
+	}
+	body := scrape(capped)
+	assert.Contains(t, body, `faro_collector_items_received_total{kind="log",tenant="a"} 2`)
+	assert.Contains(t, body, `faro_collector_items_received_total{kind="log",tenant="b"} 1`)
+	assert.Contains(t, body, `faro_collector_items_received_total{kind="log",tenant="other"} 2`)
+
+	registered := metrics.New(metrics.WithTenants(func(tenant string) bool { return tenant == "elven" }))
+	registered.ItemsDropped("elven", "limits", 1)
+	registered.ItemsDropped("random-1", "limits", 1)
+	body = scrape(registered)
+	assert.Contains(t, body, `faro_collector_items_dropped_total{reason="limits",tenant="elven"} 1`)
+	assert.Contains(t, body, `faro_collector_items_dropped_total{reason="limits",tenant="other"} 1`)
+	assert.NotContains(t, body, "random-1")
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/ndjson"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSONSinkLines(t *testing.T) {
+	var buf bytes.Buffer
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(ndjson.New(&buf)))
+	require.NoError(t, svc.Collect(context.Background(), "elven", otlpTestPayload()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var first struct {
+		Tenant    string                 `json:"tenant"`
+		Kind      string                 `json:"kind"`
+		Timestamp string                 `json:"timestamp"`
+		Level     string                 `json:"level"`
+		Message   string                 `json:"message"`
+		Fields    map[string]interface{} `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "elven", first.Tenant)
+	assert.Equal(t, "log", first.Kind)
+	assert.Equal(t, "2026-01-02T03:04:05.5Z", first.Timestamp)
+	assert.Equal(t, "warn", first.Level)
+	assert.Equal(t, "hello", first.Message)
+	assert.Equal(t, "shop", first.Fields["app"])
+	assert.Equal(t, "s-1", first.Fields["session_id"])
+	assert.Contains(t, lines[1], `"message":"TypeError: x is undefined"`)
+}
+
+func TestNDJSONRotateBySizeWithCompression(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "faro", "items.ndjson")
+	f, err := ndjson.OpenRotating(path, ndjson.RotateOptions{MaxBytes: 2000, MaxFiles: 2, Compress: true}, nil)
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(ndjson.New(f)))
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, svc.Collect(context.Background(), "elven", otlpTestPayload()))
+	}
+	require.NoError(t, f.Close())
+
+	rotated, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, rotated, 2, "older files beyond MaxFiles are removed")
+	for _, name := range rotated {
+		require.True(t, strings.HasSuffix(name, ".gz"), name)
+		lines := readGzipLines(t, name)
+		assert.NotEmpty(t, lines)
+		for _, l := range lines {
+			assert.True(t, json.Valid([]byte(l)), l)
+		}
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(2000))
+}
+
+func TestNDJSONRotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.ndjson")
+	f, err := ndjson.OpenRotating(path, ndjson.RotateOptions{Interval: 20 * time.Millisecond}, nil)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("{\"n\":1}\n"))
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = f.Write([]byte("{\"n\":2}\n"))
+	require.NoError(t, err)
+
+	rotated, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, rotated, 1)
+	old, err := os.ReadFile(rotated[0])
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":1}\n", string(old))
+	cur, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":2}\n", string(cur))
+}
+
+func TestNDJSONRotateRecoversFromFailedReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "faro")
+	path := filepath.Join(dir, "items.ndjson")
+	f, err := ndjson.OpenRotating(path, ndjson.RotateOptions{}, nil)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// The directory disappears (e.g. an unmounted volume): the rotation and the next write fail.
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, f.Rotate())
+	_, err = f.Write([]byte("{\"n\":1}\n"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrClosed)
+
+	// Once it is back, writes reopen the file instead of failing for good.
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	_, err = f.Write([]byte("{\"n\":2}\n"))
+	require.NoError(t, err)
+	cur, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":2}\n", string(cur))
+
+	require.NoError(t, f.Close())
+	_, err = f.Write([]byte("{}\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func readGzipLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	var lines []string
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	require.NoError(t, sc.Err())
+	_, err = io.Copy(io.Discard, zr)
+	require.NoError(t, err)
+	return lines
+}
+
+func TestFileSinkRequiresPath(t *testing.T) {
+	t.Setenv("SINK", "file")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrMissingFileSinkPath)
+
+	t.Setenv("FILE_SINK_PATH", config.FileSinkStdout)
+	assert.NoError(t, config.Load().Validate())
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/otlp"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type capturedRequest struct {
+	path, contentType, tenant, auth string
+	body                            []byte
+}
+
+func otlpServer(t *testing.T, status int) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var got []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, capturedRequest{r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("X-Scope-OrgID"), r.Header.Get("Authorization"), body})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func otlpTestPayload() *domain.Payload {
+	p := &domain.Payload{
+		Logs:       []domain.LogEntry{{Message: "hello", Level: "warn", Timestamp: "2026-01-02T03:04:05.5Z"}},
+		Exceptions: []domain.Exception{{Type: "TypeError", Value: "x is undefined"}},
+	}
+	p.Meta.App = domain.AppMeta{Name: "shop", Version: "1.2.3", Environment: "prod"}
+	p.Meta.Browser = domain.BrowserMeta{Name: "Firefox", Version: "130", OS: "Linux", Mobile: true}
+	p.Session = &domain.SessionMeta{ID: "s-1"}
+	return p
+}
+
+func TestOTLPSinkJSON(t *testing.T) {
+	srv, got := otlpServer(t, http.StatusOK)
+	exporter := otlp.NewExporter(srv.URL, otlp.ProtocolJSON, map[string]string{"Authorization": "Basic abc"}, time.Second)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(exporter))
+
+	require.NoError(t, svc.Collect(context.Background(), "elven", otlpTestPayload()))
+	require.Len(t, *got, 1)
+	req := (*got)[0]
+	assert.Equal(t, "/v1/logs", req.path)
+	assert.Equal(t, "application/json", req.contentType)
+	assert.Equal(t, "elven", req.tenant)
+	assert.Equal(t, "Basic abc", req.auth)
+
+	var body struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string                 `json:"key"`
+					Value map[string]interface{} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []struct {
+					TimeUnixNano   string                 `json:"timeUnixNano"`
+					SeverityNumber int                    `json:"severityNumber"`
+					SeverityText   string                 `json:"severityText"`
+					Body           map[string]interface{} `json:"body"`
+					Attributes     []struct {
+						Key   string                 `json:"key"`
+						Value map[string]interface{} `json:"value"`
+					} `json:"attributes"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	require.NoError(t, json.Unmarshal(req.body, &body))
+	require.Len(t, body.ResourceLogs, 1)
+
+	resource := map[string]interface{}{}
+	for _, kv := range body.ResourceLogs[0].Resource.Attributes {
+		for _, v := range kv.Value {
+			resource[kv.Key] = v
+		}
+	}
+	assert.Equal(t, "shop", resource["service.name"])
+	assert.Equal(t, "1.2.3", resource["service.version"])
+	assert.Equal(t, "prod", resource["deployment.environment"])
+	assert.Equal(t, "Firefox", resource["browser.name"])
+	assert.Equal(t, true, resource["browser.mobile"])
+
+	records := body.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assert.Equal(t, "1767323045500000000", records[0].TimeUnixNano)
+	assert.Equal(t, 13, records[0].SeverityNumber)
+	assert.Equal(t, "hello", records[0].Body["stringValue"])
+	assert.Equal(t, 17, records[1].SeverityNumber)
+	assert.Equal(t, "TypeError: x is undefined", records[1].Body["stringValue"])
+
+	attrs := map[string]interface{}{}
+	for _, kv := range records[1].Attributes {
+		attrs[kv.Key] = kv.Value["stringValue"]
+	}
+	assert.Equal(t, "exception", attrs["faro.kind"])
+	assert.Equal(t, "s-1", attrs["session.id"])
+	assert.Equal(t, "TypeError", attrs["exception.type"])
+}
+
+func TestOTLPSinkProtobuf(t *testing.T) {
+	srv, got := otlpServer(t, http.StatusOK)
+	exporter := otlp.NewExporter(srv.URL+"/custom/logs", otlp.ProtocolProtobuf, nil, time.Second)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(exporter))
+
+	require.NoError(t, svc.Collect(context.Background(), "elven", otlpTestPayload()))
+	require.Len(t, *got, 1)
+	req := (*got)[0]
+	assert.Equal(t, "/custom/logs", req.path)
+	assert.Equal(t, "application/x-protobuf", req.contentType)
+
+	// ExportLogsServiceRequest.resource_logs[0].resource.attributes[0] is service.name.
+	rl := protoField(t, req.body, 1)
+	res := protoField(t, rl, 1)
+	kv := protoField(t, res, 1)
+	assert.Equal(t, "service.name", string(protoField(t, kv, 1)))
+	assert.Equal(t, "shop", string(protoField(t, protoField(t, kv, 2), 1)))
+}
+
+func TestOTLPSinkError(t *testing.T) {
+	srv, _ := otlpServer(t, http.StatusServiceUnavailable)
+	exporter := otlp.NewExporter(srv.URL, otlp.ProtocolJSON, nil, time.Second)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(exporter))
+
+	err := svc.Collect(context.Background(), "elven", otlpTestPayload())
+	assert.ErrorIs(t, err, domain.ErrSinkSend)
+}
+
+// protoField returns the first length-delimited field num of msg.
+func protoField(t *testing.T, msg []byte, num protowire.Number) []byte {
+	t.Helper()
+	var found []byte
+	forEachField(msg, func(n protowire.Number, v []byte) {
+		if n == num && found == nil {
+			found = v
+		}
+	})
+	require.NotNil(t, found, "field %d not found", num)
+	return found
+}
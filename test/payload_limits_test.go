@@ -0,0 +1,121 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.MaxBodyBytes = 2048
+	svc := usecase.NewCollectorService(noopLoki{}, nil, usecase.WithLimits(domain.Limits{
+		MaxItemsPerType: 2,
+		MaxStringLength: 64,
+		MaxMapEntries:   3,
+	}))
+	router := httpadapter.Router(cfg, svc)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	logs := func(n int, msg string) string {
+		entries := make([]string, n)
+		for i := range entries {
+			entries[i] = `{"message":"` + msg + `","level":"info"}`
+		}
+		return `{"meta":{"app":{"name":"t"}},"logs":[` + strings.Join(entries, ",") + `]}`
+	}
+
+	long := strings.Repeat("x", 65)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Within limits", body: logs(2, "ok"), expectedStatus: 200},
+		{name: "Body too large", body: logs(1, strings.Repeat("x", 4096)), expectedStatus: 413},
+		{name: "Too many items", body: logs(3, "ok"), expectedStatus: 413},
+		{name: "String too long", body: logs(1, strings.Repeat("x", 65)), expectedStatus: 413},
+		{name: "Log level too long", body: `{"meta":{},"logs":[{"message":"ok","level":"` + long + `"}]}`, expectedStatus: 413},
+		{name: "Event domain too long", body: `{"meta":{},"events":[{"name":"click","domain":"` + long + `"}]}`, expectedStatus: 413},
+		{name: "Measurement type too long", body: `{"meta":{},"measurements":[{"type":"` + long + `","values":{"a":1}}]}`, expectedStatus: 413},
+		{name: "Measurement value name too long", body: `{"meta":{},"measurements":[{"type":"web-vitals","values":{"` + long + `":1}}]}`, expectedStatus: 413},
+		{
+			name:           "Stack frame filename too long",
+			body:           `{"meta":{},"exceptions":[{"type":"Error","value":"boom","stacktrace":{"frames":[{"filename":"` + long + `","function":"f"}]}}]}`,
+			expectedStatus: 413,
+		},
+		{
+			name:           "Stack frame fields checked separately",
+			body:           `{"meta":{},"exceptions":[{"type":"Error","value":"boom","stacktrace":{"frames":[{"filename":"` + strings.Repeat("f", 40) + `","function":"` + strings.Repeat("g", 40) + `"}]}}]}`,
+			expectedStatus: 200,
+		},
+		{name: "Browser name too long", body: `{"meta":{"browser":{"name":"` + long + `"}}}`, expectedStatus: 413},
+		{name: "Top-level page URL too long", body: `{"meta":{},"page":{"url":"` + long + `"}}`, expectedStatus: 413},
+		{
+			name:           "Too many attributes",
+			body:           `{"meta":{},"events":[{"name":"click","attributes":{"a":1,"b":2,"c":3,"d":4}}]}`,
+			expectedStatus: 413,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/collect/elven/"+token, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestMetaDecode(t *testing.T) {
+	var p domain.Payload
+	raw := `{
+		"meta": {
+			"app": {"name": "shop", "version": "1.2.3"},
+			"browser": "not-an-object",
+			"session": {"id": "s-1"},
+			"page": {"url": "https://shop.example/cart"},
+			"k8s": {"pod": "web-1"},
+			"release": "r42"
+		},
+		"logs": [{"message": "hi", "level": "info"}]
+	}`
+	require.NoError(t, json.Unmarshal([]byte(raw), &p))
+	assert.Equal(t, "shop", p.Meta.App.Name)
+	assert.Equal(t, "s-1", p.Meta.Session.ID)
+	assert.Equal(t, "https://shop.example/cart", p.Meta.Page.URL)
+	assert.Empty(t, p.Meta.Browser.Name)
+	assert.Equal(t, map[string]interface{}{"pod": "web-1"}, p.Meta.Extra["k8s"])
+	assert.Equal(t, "r42", p.Meta.Extra["release"])
+	assert.Len(t, p.Logs, 1)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"meta":{"app":{"name":}}}`), &p))
+
+	// meta is required, as before the single-pass decoder; null is still accepted.
+	assert.Error(t, json.Unmarshal([]byte(`{"logs":[{"message":"hi"}]}`), &domain.Payload{}))
+	assert.NoError(t, json.Unmarshal([]byte(`{"meta":null,"logs":[{"message":"hi"}]}`), &domain.Payload{}))
+}
+
+func TestInvalidLimitEnvIsReported(t *testing.T) {
+	t.Setenv("MAX_BODY_BYTES", "5MB")
+	t.Setenv("SINK_TIMEOUT", "10")
+	err := config.Load().Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MAX_BODY_BYTES")
+	assert.Contains(t, err.Error(), "SINK_TIMEOUT")
+}
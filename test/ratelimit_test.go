@@ -0,0 +1,169 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := generateJWT(jwt.MapClaims{"role": "user", "iss": "trusted-issuer"})
+
+	mustParse := func(spec string) config.RateLimit {
+		l, err := config.ParseRateLimit(spec)
+		require.NoError(t, err)
+		return l
+	}
+
+	tests := []struct {
+		name         string
+		limits       config.RateLimitConfig
+		tenants      []string
+		expectedCode []int
+		expectedErr  httpadapter.ErrorCode
+	}{
+		{
+			name:         "Tenant requests per second",
+			limits:       config.RateLimitConfig{Tenant: mustParse("rps=1")},
+			tenants:      []string{"elven", "elven"},
+			expectedCode: []int{200, 429},
+			expectedErr:  httpadapter.CodeRateLimited,
+		},
+		{
+			name:         "Tenant override",
+			limits:       config.RateLimitConfig{Tenant: mustParse("rps=1"), TenantOverrides: map[string]config.RateLimit{"big": mustParse("rps=100")}},
+			tenants:      []string{"big", "big", "big"},
+			expectedCode: []int{200, 200, 200},
+		},
+		{
+			name:         "Items per second",
+			limits:       config.RateLimitConfig{Tenant: mustParse("items=1")},
+			tenants:      []string{"elven", "elven"},
+			expectedCode: []int{200, 429},
+			expectedErr:  httpadapter.CodeRateLimited,
+		},
+		{
+			name:         "Per IP across tenants",
+			limits:       config.RateLimitConfig{IP: mustParse("rps=1")},
+			tenants:      []string{"a", "b"},
+			expectedCode: []int{200, 429},
+			expectedErr:  httpadapter.CodeRateLimited,
+		},
+		{
+			name:         "Daily quota",
+			limits:       config.RateLimitConfig{Tenant: mustParse("daily_items=2")},
+			tenants:      []string{"elven", "elven", "elven", "other"},
+			expectedCode: []int{200, 200, 429, 200},
+			expectedErr:  httpadapter.CodeQuotaExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.RateLimits = tt.limits
+			router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+			for i, tenant := range tt.tenants {
+				req := httptest.NewRequest(http.MethodPost, "/collect/"+tenant+"/"+token, strings.NewReader(minimalCollectPayload))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				require.Equal(t, tt.expectedCode[i], w.Code, "request %d", i)
+				if w.Code == http.StatusTooManyRequests {
+					assert.NotEmpty(t, w.Header().Get("Retry-After"))
+					var body httpadapter.ErrorResponse
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+					assert.Equal(t, tt.expectedErr, body.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	l, err := config.ParseRateLimit("rps=5, items=100,bytes=65536,burst=2,daily_items=1000")
+	require.NoError(t, err)
+	assert.Equal(t, config.RateLimit{RequestsPerSec: 5, ItemsPerSec: 100, BytesPerSec: 65536, BurstSeconds: 2, DailyItems: 1000}, l)
+
+	_, err = config.ParseRateLimit("rps=fast")
+	assert.Error(t, err)
+	_, err = config.ParseRateLimit("qps=1")
+	assert.Error(t, err)
+	for _, spec := range []string{"rps=NaN", "items=+Inf", "bytes=inf", "burst=NaN"} {
+		_, err = config.ParseRateLimit(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestIPRateLimitRunsBeforeAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.RateLimits = config.RateLimitConfig{IP: config.RateLimit{RequestsPerSec: 1, BurstSeconds: 1}}
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+
+	var codes []int
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer not-a-jwt")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+}
+
+func TestIPRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.RateLimits = config.RateLimitConfig{IP: config.RateLimit{RequestsPerSec: 1, BurstSeconds: 1}}
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+
+	var codes []int
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer not-a-jwt")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusTooManyRequests}, codes, "a forged X-Forwarded-For must not get a fresh bucket")
+
+	// Behind a configured proxy the forwarded address is the client.
+	cfg.TrustedProxies = []string{"203.0.113.0/24"}
+	router = httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil))
+	codes = nil
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		req.Header.Set("Authorization", "Bearer not-a-jwt")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized}, codes)
+}
+
+func TestTrustedProxiesValidation(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+	assert.NoError(t, config.Load().Validate())
+	t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrInvalidTrustedProxies)
+}
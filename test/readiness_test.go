@@ -0,0 +1,226 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/loki"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	sink := &recordingSink{err: errors.New("loki down")}
+	b := usecase.NewCircuitBreaker(sink, 2, 50*time.Millisecond)
+	ctx := context.Background()
+	items := []domain.Item{{Kind: "log"}}
+
+	assert.Error(t, b.Write(ctx, "elven", items))
+	assert.Equal(t, usecase.BreakerClosed, b.State())
+	assert.Error(t, b.Write(ctx, "elven", items))
+	assert.Equal(t, usecase.BreakerOpen, b.State())
+
+	// While open the sink is not called.
+	assert.ErrorIs(t, b.Write(ctx, "elven", items), usecase.ErrCircuitOpen)
+	assert.Len(t, sink.got("elven"), 2)
+
+	// A failed trial reopens the circuit for another cooldown.
+	require.Eventually(t, func() bool { return b.State() == usecase.BreakerHalfOpen }, time.Second, 5*time.Millisecond)
+	assert.Error(t, b.Write(ctx, "elven", items))
+	assert.Equal(t, usecase.BreakerOpen, b.State())
+
+	require.Eventually(t, func() bool { return b.State() == usecase.BreakerHalfOpen }, time.Second, 5*time.Millisecond)
+	sink.mu.Lock()
+	sink.err = nil
+	sink.mu.Unlock()
+	assert.NoError(t, b.Write(ctx, "elven", items))
+	assert.Equal(t, usecase.BreakerClosed, b.State())
+}
+
+func TestCircuitBreakerIgnoresCallerErrors(t *testing.T) {
+	b := usecase.NewCircuitBreaker(&recordingSink{err: domain.ErrUnknownTenant}, 1, time.Minute)
+	assert.ErrorIs(t, b.Write(context.Background(), "ghost", nil), domain.ErrUnknownTenant)
+	assert.Equal(t, usecase.BreakerClosed, b.State())
+
+	disabled := usecase.NewCircuitBreaker(&recordingSink{err: errors.New("down")}, 0, time.Minute)
+	for range 3 {
+		assert.NotErrorIs(t, disabled.Write(context.Background(), "elven", nil), usecase.ErrCircuitOpen)
+	}
+}
+
+func TestCircuitBreakerIgnoresRejectedItems(t *testing.T) {
+	sink := &recordingSink{err: fmt.Errorf("%w: loki returned 429: rate limited", domain.ErrSinkRejected)}
+	b := usecase.NewCircuitBreaker(sink, 1, time.Minute)
+	for range 3 {
+		assert.ErrorIs(t, b.Write(context.Background(), "elven", []domain.Item{{Kind: "log"}}), domain.ErrSinkRejected)
+	}
+	assert.Equal(t, usecase.BreakerClosed, b.State())
+	assert.Len(t, sink.got("elven"), 3)
+}
+
+func TestCircuitBreakerPerKey(t *testing.T) {
+	sink := &recordingSink{err: errors.New("connection refused")}
+	endpoints := map[string]string{"acme": "https://loki-a", "beta": "https://loki-b", "gamma": "https://loki-b"}
+	b := usecase.NewCircuitBreaker(sink, 1, time.Minute, usecase.WithBreakerKey(func(tenantID string) string {
+		return endpoints[tenantID]
+	}))
+	ctx := context.Background()
+	items := []domain.Item{{Kind: "log"}}
+
+	assert.Error(t, b.Write(ctx, "acme", items))
+	assert.ErrorIs(t, b.Write(ctx, "acme", items), usecase.ErrCircuitOpen)
+
+	// beta writes to another endpoint, so acme's open circuit does not stop it.
+	sink.mu.Lock()
+	sink.err = nil
+	sink.mu.Unlock()
+	assert.NoError(t, b.Write(ctx, "beta", items))
+	assert.Equal(t, usecase.BreakerClosed, b.State(), "a sink with a working endpoint stays ready")
+
+	sink.mu.Lock()
+	sink.err = errors.New("503")
+	sink.mu.Unlock()
+	assert.Error(t, b.Write(ctx, "gamma", items))
+	assert.ErrorIs(t, b.Write(ctx, "beta", items), usecase.ErrCircuitOpen, "beta and gamma share an endpoint")
+	assert.Equal(t, usecase.BreakerOpen, b.State())
+	assert.Len(t, sink.got("acme"), 1)
+	assert.Len(t, sink.got("beta"), 1)
+}
+
+func TestLokiClientMarksClientErrorsAsRejected(t *testing.T) {
+	status := http.StatusTooManyRequests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	c := loki.New(loki.Options{URL: srv.URL})
+	streams := []domain.LokiStream{{Stream: map[string]string{"app": "t"}, Values: [][]string{{"1", "x"}}}}
+
+	for _, status = range []int{http.StatusTooManyRequests, http.StatusBadRequest} {
+		assert.ErrorIs(t, c.Push(context.Background(), "elven", streams), domain.ErrSinkRejected, status)
+	}
+	status = http.StatusServiceUnavailable
+	err := c.Push(context.Background(), "elven", streams)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrSinkRejected)
+}
+
+func TestReadyzReportsComponents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	readiness := httpadapter.NewReadiness()
+	var queueErr error
+	readiness.AddCheck("loki", func() error { return nil })
+	readiness.AddCheck("queue:loki", func() error { return queueErr })
+	cfg := testConfig(t)
+	cfg.AdminToken = "admin-token-with-at-least-32-characters"
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(nil, nil), httpadapter.WithReadiness(readiness))
+
+	authorization := ""
+	get := func(path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"status": "ok"}, body)
+
+	queueErr = errors.New("queue 95/100 items")
+	// Component details name sink hosts and errors: only the admin token gets them.
+	for _, authorization = range []string{"", "Bearer wrong-token"} {
+		code, body = get("/readyz?verbose")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, map[string]any{"status": "fail"}, body)
+	}
+
+	authorization = "Bearer " + cfg.AdminToken
+	code, body = get("/readyz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", body["status"])
+	assert.Equal(t, map[string]any{
+		"loki":       map[string]any{"status": "ok"},
+		"queue:loki": map[string]any{"status": "fail", "error": "queue 95/100 items"},
+	}, body["checks"])
+
+	readiness.SetDraining()
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "draining", body["status"])
+
+	// Liveness ignores components and shutdown.
+	for _, path := range []string{"/livez", "/health"} {
+		code, body = get(path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, "ok", body["status"], path)
+	}
+}
+
+func TestLokiProber(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	lokiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ready", r.URL.Path)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer lokiSrv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	targets := []loki.Options{{URL: lokiSrv.URL, Auth: loki.Auth{Token: "secret"}}, {URL: down.URL}}
+	p := loki.NewProber(func() []loki.Options { return targets }, time.Minute, nil)
+	assert.Error(t, p.Err(), "not ready before the first probe")
+
+	p.Probe(context.Background())
+	err := p.Err()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Contains(t, err.Error(), "unreachable")
+	assert.NotContains(t, err.Error(), "secret")
+
+	// One ready endpoint is enough.
+	status.Store(http.StatusOK)
+	p.Probe(context.Background())
+	assert.NoError(t, p.Err())
+}
+
+func TestCollectReturns503WhenCircuitOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	b := usecase.NewCircuitBreaker(&recordingSink{err: errors.New("loki down")}, 1, time.Minute)
+	router := httpadapter.Router(testConfig(t), usecase.NewCollectorService(nil, nil, usecase.WithSink(b)))
+	token := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, post().Code)
+	w := post()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
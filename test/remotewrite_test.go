@@ -0,0 +1,216 @@
+package test
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/metrics"
+	"collector-fe-instrumentation/internal/adapter/remotewrite"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fakeReceiver is a remote-write endpoint that decodes and keeps what it receives.
+type fakeReceiver struct {
+	mu       sync.Mutex
+	failures int // respond 503 this many times first
+	byTenant map[string]map[string]float64
+	requests int
+}
+
+func (f *fakeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Authorization") != "Bearer rw-token" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	compressed, _ := io.ReadAll(r.Body)
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	tenant := r.Header.Get("X-Scope-OrgID")
+	if f.byTenant == nil {
+		f.byTenant = make(map[string]map[string]float64)
+	}
+	if f.byTenant[tenant] == nil {
+		f.byTenant[tenant] = make(map[string]float64)
+	}
+	for key, v := range decodeWriteRequest(raw) {
+		f.byTenant[tenant][key] = v
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeWriteRequest returns "name{l1=v1,...}" -> last sample value.
+func decodeWriteRequest(b []byte) map[string]float64 {
+	out := make(map[string]float64)
+	forEachField(b, func(num protowire.Number, ts []byte) {
+		var name string
+		var labels []string
+		var value float64
+		forEachField(ts, func(num protowire.Number, v []byte) {
+			switch num {
+			case 1:
+				var ln, lv string
+				forEachField(v, func(num protowire.Number, s []byte) {
+					if num == 1 {
+						ln = string(s)
+					} else {
+						lv = string(s)
+					}
+				})
+				if ln == "__name__" {
+					name = lv
+				} else {
+					labels = append(labels, ln+"="+lv)
+				}
+			case 2:
+				bits, _ := protowire.ConsumeFixed64(v[1:])
+				value = math.Float64frombits(bits)
+			}
+		})
+		sort.Strings(labels)
+		out[name+"{"+strings.Join(labels, ",")+"}"] = value
+	})
+	return out
+}
+
+// forEachField walks length-delimited fields of a message.
+func forEachField(b []byte, fn func(protowire.Number, []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		fn(num, v)
+		b = b[n:]
+	}
+}
+
+func TestRemoteWriteFlush(t *testing.T) {
+	receiver := &fakeReceiver{failures: 1}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	rum := metrics.NewRUM(reg, 100, 0)
+	svc := usecase.NewCollectorService(noopLoki{}, nil, usecase.WithObserver(rum))
+
+	p := &domain.Payload{
+		Events:       []domain.Event{{Name: "session_start"}, {Name: "view_changed"}},
+		Exceptions:   []domain.Exception{{Type: "TypeError"}},
+		Measurements: []domain.Measurement{{Type: "web-vitals", Values: map[string]float64{"lcp": 1500}}},
+	}
+	p.Meta.App = domain.AppMeta{Name: "shop", Environment: "prod"}
+	p.Meta.View.Name = "home"
+	p.Meta.Browser.Name = "Firefox"
+	require.NoError(t, svc.Collect(context.Background(), "elven", p))
+	require.NoError(t, svc.Collect(context.Background(), "acme", p))
+
+	client := remotewrite.NewClient(srv.URL+"/api/v1/push", "rw-token", time.Second, 2)
+	flusher := remotewrite.NewFlusher(client, reg, "faro_rum_", time.Minute, nil)
+	require.NoError(t, flusher.Flush(context.Background()))
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	assert.Equal(t, 3, receiver.requests) // one 503 retried, then one per tenant
+	require.Contains(t, receiver.byTenant, "elven")
+	require.Contains(t, receiver.byTenant, "acme")
+
+	got := receiver.byTenant["elven"]
+	base := "app=shop,browser=Firefox,environment=prod,view=home"
+	vital := func(suffix, le string) string {
+		if le != "" {
+			le = "le=" + le + ","
+		}
+		return "faro_rum_web_vital_seconds_" + suffix + "{app=shop,browser=Firefox,environment=prod," + le + "name=lcp,view=home}"
+	}
+	assert.Equal(t, 1.0, got["faro_rum_sessions_total{app=shop,environment=prod}"])
+	assert.Equal(t, 1.0, got["faro_rum_page_views_total{"+base+"}"])
+	assert.Equal(t, 1.0, got["faro_rum_errors_total{"+base+"}"])
+	assert.Equal(t, 1.0, got[vital("count", "")])
+	assert.Equal(t, 1.5, got[vital("sum", "")])
+	assert.Equal(t, 1.0, got[vital("bucket", "1.8")])
+	assert.Equal(t, 0.0, got[vital("bucket", "1")])
+	assert.Equal(t, 1.0, got[vital("bucket", "+Inf")])
+	for key := range got {
+		assert.NotContains(t, key, "tenant=")
+	}
+}
+
+func TestRemoteWriteDoesNotRetryClientErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	client := remotewrite.NewClient(srv.URL, "", time.Second, 3)
+	err := client.Write(context.Background(), "elven", []remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "__name__", Value: "x"}},
+		Samples: []remotewrite.Sample{{Value: 1, TimestampMs: time.Now().UnixMilli()}},
+	}})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRemoteWriteKeepsTenantsBeyondLabelCap(t *testing.T) {
+	receiver := &fakeReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	rum := metrics.NewRUM(reg, 2, 0)
+	tenants := []string{"t1", "t2", "t3", "t4"}
+	for _, tenant := range tenants {
+		for _, view := range []string{"home", "cart", "checkout"} {
+			p := &domain.Payload{Exceptions: []domain.Exception{{Type: "TypeError"}}}
+			p.Meta.View.Name = view
+			rum.ObservePayload(tenant, p)
+		}
+	}
+
+	client := remotewrite.NewClient(srv.URL+"/api/v1/push", "rw-token", time.Second, 0)
+	require.NoError(t, remotewrite.NewFlusher(client, reg, "faro_rum_", time.Minute, nil).Flush(context.Background()))
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	assert.NotContains(t, receiver.byTenant, metrics.OverflowLabel)
+	for _, tenant := range tenants {
+		// Each tenant keeps its own two views; only its third one overflows.
+		got := receiver.byTenant[tenant]
+		require.NotNil(t, got, tenant)
+		base := "faro_rum_errors_total{app=unknown,browser=unknown,environment=unknown,view="
+		assert.Equal(t, 1.0, got[base+"home}"], tenant)
+		assert.Equal(t, 1.0, got[base+"cart}"], tenant)
+		assert.Equal(t, 1.0, got[base+"other}"], tenant)
+	}
+}
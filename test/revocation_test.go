@@ -0,0 +1,84 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/revocation"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.AdminToken = "admin-token-with-at-least-32-characters"
+
+	revokedByHash := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer", "jti": "hash-me"})
+	path := filepath.Join(t.TempDir(), "revoked.txt")
+	content := "# revoked tokens\njti:leaked-1\n" + revocation.HashToken(revokedByHash) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	list, err := revocation.Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Len())
+
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil), httpadapter.WithRevocationList(list))
+	collect := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/collect/elven/"+token, strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 401, collect(generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer", "jti": "leaked-1"})))
+	assert.Equal(t, 401, collect(revokedByHash))
+	later := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer", "jti": "leaked-2"})
+	assert.Equal(t, 200, collect(later))
+
+	require.NoError(t, os.WriteFile(path, []byte(content+"jti:leaked-2\n"), 0o600))
+
+	reload := func(auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/revocations/reload", nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, 401, reload("Bearer wrong"))
+	assert.Equal(t, 200, reload("Bearer "+cfg.AdminToken))
+	assert.Equal(t, 401, collect(later))
+}
+
+func TestRevocationList_InvalidFileKeepsPrevious(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked.txt")
+	require.NoError(t, os.WriteFile(path, []byte("jti:a\n"), 0o600))
+	list, err := revocation.Load(path, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("not-an-entry\n"), 0o600))
+	_, err = list.Reload()
+	assert.Error(t, err)
+	assert.True(t, list.IsRevoked("a", "irrelevant"))
+}
+
+func TestRevocationReloadIntervalMustBePositive(t *testing.T) {
+	t.Setenv("REVOCATION_FILE", filepath.Join(t.TempDir(), "revoked.json"))
+	for _, v := range []string{"0", "-5s"} {
+		t.Setenv("REVOCATION_RELOAD_INTERVAL", v)
+		assert.ErrorIs(t, config.Load().Validate(), config.ErrInvalidRevocationReload, v)
+	}
+	t.Setenv("REVOCATION_RELOAD_INTERVAL", "10s")
+	assert.NoError(t, config.Load().Validate())
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"collector-fe-instrumentation/internal/adapter/metrics"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRUMMetrics(t *testing.T) {
+	m := metrics.New()
+	rum := metrics.NewRUM(m.Registry, 2, 0)
+	svc := usecase.NewCollectorService(noopLoki{}, nil, usecase.WithObserver(rum))
+
+	payload := func(view string, values map[string]float64) *domain.Payload {
+		p := &domain.Payload{Measurements: []domain.Measurement{{Type: "web-vitals", Values: values}}}
+		p.Meta.App = domain.AppMeta{Name: "shop", Environment: "prod"}
+		p.Meta.Browser.Name = "Chrome"
+		p.Meta.View.Name = view
+		return p
+	}
+	ctx := context.Background()
+	require.NoError(t, svc.Collect(ctx, "elven", payload("home", map[string]float64{"lcp": 1800, "cls": 0.05})))
+	require.NoError(t, svc.Collect(ctx, "elven", payload("cart", map[string]float64{"lcp": 3200, "inp": 120})))
+	require.NoError(t, svc.Collect(ctx, "elven", payload("checkout", map[string]float64{"lcp": 900})))
+	require.NoError(t, svc.Collect(ctx, "elven", payload("home", map[string]float64{"custom_metric": 42})))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`faro_rum_web_vital_seconds_bucket{app="shop",browser="Chrome",environment="prod",name="lcp",tenant="elven",view="home",le="1.8"} 1`,
+		`faro_rum_web_vital_seconds_count{app="shop",browser="Chrome",environment="prod",name="lcp",tenant="elven",view="cart"} 1`,
+		`faro_rum_web_vital_seconds_sum{app="shop",browser="Chrome",environment="prod",name="inp",tenant="elven",view="cart"} 0.12`,
+		// third distinct view is over the cap of 2
+		`faro_rum_web_vital_seconds_count{app="shop",browser="Chrome",environment="prod",name="lcp",tenant="elven",view="other"} 1`,
+		`faro_rum_cls_count{app="shop",browser="Chrome",environment="prod",tenant="elven",view="home"} 1`,
+		`faro_rum_measurement_value_sum{app="shop",browser="Chrome",environment="prod",name="custom_metric",tenant="elven",type="web-vitals",view="home"} 42`,
+	} {
+		assert.Contains(t, body, want)
+	}
+	assert.NotContains(t, body, `view="checkout"`)
+}
+
+func TestRUMLabelCapsArePerTenant(t *testing.T) {
+	m := metrics.New()
+	rum := metrics.NewRUM(m.Registry, 2, 0)
+
+	view := func(name string) *domain.Payload {
+		p := &domain.Payload{Exceptions: []domain.Exception{{Type: "TypeError"}}}
+		p.Meta.View.Name = name
+		return p
+	}
+	// A noisy tenant using up its views leaves the other tenant's untouched.
+	rum.ObservePayload("noisy", view("a"))
+	rum.ObservePayload("noisy", view("b"))
+	rum.ObservePayload("noisy", view("c"))
+	rum.ObservePayload("quiet", view("home"))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `faro_rum_errors_total{app="unknown",browser="unknown",environment="unknown",tenant="noisy",view="other"} 1`)
+	assert.Contains(t, body, `faro_rum_errors_total{app="unknown",browser="unknown",environment="unknown",tenant="quiet",view="home"} 1`)
+}
+
+func TestRUMSeriesCap(t *testing.T) {
+	m := metrics.New()
+	rum := metrics.NewRUM(m.Registry, 0, 2)
+
+	view := func(name string) *domain.Payload {
+		p := &domain.Payload{Exceptions: []domain.Exception{{Type: "TypeError"}}}
+		p.Meta.View.Name = name
+		return p
+	}
+	for _, v := range []string{"a", "b", "c", "a"} {
+		rum.ObservePayload("elven", view(v))
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `view="a"} 2`)
+	assert.Contains(t, body, `view="b"} 1`)
+	assert.NotContains(t, body, `view="c"`, "a third series is over the cap")
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return ln
+}
+
+// startServer runs srv on ln (and redirectLn) until the test ends.
+func startServer(t *testing.T, srv *httpadapter.Server, ln, redirectLn net.Listener) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln, redirectLn) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, srv.Shutdown(ctx))
+		assert.NoError(t, <-done)
+	})
+}
+
+func tlsClient(ca *testCA, serverName string) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool, ServerName: serverName, MinVersion: tls.VersionTLS12},
+			ForceAttemptHTTP2: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func TestServerTLSWithHTTP2AndCertReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certPEM, keyPEM := ca.issue(t, "collector-v1", "collector.test")
+	certFile := writePEM(t, dir, "tls.crt", certPEM, start)
+	keyFile := writePEM(t, dir, "tls.key", keyPEM, start)
+
+	ln, redirectLn := listen(t), listen(t)
+	srv, err := httpadapter.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), httpadapter.ServerOptions{
+		Addr: ln.Addr().String(), CertFile: certFile, KeyFile: keyFile, RedirectAddr: redirectLn.Addr().String(),
+	})
+	require.NoError(t, err)
+	require.True(t, srv.TLS())
+	startServer(t, srv, ln, redirectLn)
+
+	url := "https://" + ln.Addr().String() + "/health"
+	client := tlsClient(ca, "collector.test")
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "collector-v1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	certPEM, keyPEM = ca.issue(t, "collector-v2", "collector.test")
+	writePEM(t, dir, "tls.crt", certPEM, start.Add(30*time.Second))
+	writePEM(t, dir, "tls.key", keyPEM, start.Add(30*time.Second))
+	client = tlsClient(ca, "collector.test")
+	resp, err = client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "collector-v2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	resp, err = client.Post("http://"+redirectLn.Addr().String()+"/collect/elven?x=1", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+	assert.Equal(t, "https://127.0.0.1:"+port+"/collect/elven?x=1", resp.Header.Get("Location"))
+}
+
+// fakeACME is a local ACME directory stand-in that records requests and refuses to register accounts.
+type fakeACME struct {
+	srv   *httptest.Server
+	mu    sync.Mutex
+	paths []string
+}
+
+func newFakeACME(t *testing.T) *fakeACME {
+	f := &fakeACME{}
+	f.srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+		f.mu.Unlock()
+		switch r.URL.Path {
+		case "/directory":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"newNonce":   f.srv.URL + "/nonce",
+				"newAccount": f.srv.URL + "/account",
+				"newOrder":   f.srv.URL + "/order",
+			})
+		case "/nonce":
+			w.Header().Set("Replay-Nonce", "nonce")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"type":"urn:ietf:params:acme:error:unauthorized","detail":"stand-in"}`))
+		}
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeACME) requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.paths...)
+}
+
+func TestServerACMEUsesConfiguredDirectory(t *testing.T) {
+	acme := newFakeACME(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "acme-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: acme.srv.Certificate().Raw}), 0o600))
+
+	ln := listen(t)
+	srv, err := httpadapter.NewServer(http.NotFoundHandler(), httpadapter.ServerOptions{
+		Addr:             ln.Addr().String(),
+		ACMEDomains:      []string{"collector.test"},
+		ACMEDirectoryURL: acme.srv.URL + "/directory",
+		ACMECAFile:       caFile,
+		ACMECacheDir:     filepath.Join(dir, "cache"),
+	})
+	require.NoError(t, err)
+	startServer(t, srv, ln, nil)
+
+	handshake := func(serverName string) error {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{ServerName: serverName, InsecureSkipVerify: true}) // #nosec G402 -- only the handshake outcome matters
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// Hosts outside ACME_DOMAINS never reach the CA.
+	assert.Error(t, handshake("other.test"))
+	assert.Empty(t, acme.requests())
+
+	// The stand-in refuses the account, so the handshake fails after talking to it.
+	assert.Error(t, handshake("collector.test"))
+	assert.Contains(t, acme.requests(), "GET /directory")
+}
+
+func TestServerOptionsValidation(t *testing.T) {
+	_, err := httpadapter.NewServer(http.NotFoundHandler(), httpadapter.ServerOptions{Addr: ":0", RedirectAddr: ":0"})
+	assert.Error(t, err, "redirect needs TLS")
+
+	_, err = httpadapter.NewServer(http.NotFoundHandler(), httpadapter.ServerOptions{Addr: ":0", CertFile: "missing.crt", KeyFile: "missing.key"})
+	assert.Error(t, err)
+
+	srv, err := httpadapter.NewServer(http.NotFoundHandler(), httpadapter.ServerOptions{Addr: ":0"})
+	require.NoError(t, err)
+	assert.False(t, srv.TLS())
+}
+
+func TestACMEPortWarning(t *testing.T) {
+	t.Setenv("ACME_DOMAINS", "collector.example.com")
+	t.Setenv("PORT", "8443")
+	cfg := config.Load()
+	require.NoError(t, cfg.Validate())
+	require.Len(t, cfg.Warnings(), 1)
+	assert.Contains(t, cfg.Warnings()[0], "ACME_DOMAINS")
+
+	t.Setenv("HTTP_REDIRECT_PORT", "80")
+	assert.Empty(t, config.Load().Warnings(), "http-01 is reachable on port 80")
+
+	t.Setenv("HTTP_REDIRECT_PORT", "")
+	t.Setenv("PORT", "443")
+	assert.Empty(t, config.Load().Warnings(), "tls-alpn-01 is reachable on port 443")
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gateSink signals when a write starts and holds it until release is closed.
+type gateSink struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *gateSink) Write(ctx context.Context, _ string, _ []domain.Item) error {
+	s.entered <- struct{}{}
+	select {
+	case <-s.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestDrainFailsReadinessThenFinishesInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	sink := &gateSink{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	readiness := httpadapter.NewReadiness()
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(nil, nil, usecase.WithSink(sink)), httpadapter.WithReadiness(readiness))
+
+	ln := listen(t)
+	srv, err := httpadapter.NewServer(router, httpadapter.ServerOptions{Addr: ln.Addr().String()})
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln, nil) }()
+	base := "http://" + ln.Addr().String()
+
+	status := func(path string) int {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusOK, status("/readyz"))
+
+	token := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"})
+	collected := make(chan int, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, base+"/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			collected <- 0
+			return
+		}
+		resp.Body.Close()
+		collected <- resp.StatusCode
+	}()
+	<-sink.entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() { drained <- srv.Drain(ctx, readiness, 200*time.Millisecond) }()
+
+	// During the delay the listener is still up but readiness fails.
+	assert.Eventually(t, func() bool { return status("/readyz") == http.StatusServiceUnavailable }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, status("/health"))
+	select {
+	case <-drained:
+		t.Fatal("drain returned while a request was in flight")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	close(sink.release)
+	assert.Equal(t, http.StatusOK, <-collected)
+	require.NoError(t, <-drained)
+	require.NoError(t, <-served)
+	assert.Equal(t, 0, status("/health"), "listener closed after drain")
+}
+
+func TestDrainGivesUpAtDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	sink := &gateSink{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(sink.release)
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(nil, nil, usecase.WithSink(sink)))
+
+	ln := listen(t)
+	srv, err := httpadapter.NewServer(router, httpadapter.ServerOptions{Addr: ln.Addr().String()})
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(ln, nil) }()
+
+	token := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"})
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+ln.Addr().String()+"/collect/elven", strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-sink.entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = srv.Drain(ctx, nil, time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
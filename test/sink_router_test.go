@@ -0,0 +1,167 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps the kinds it received per tenant; block, when set, holds every write until closed.
+type recordingSink struct {
+	mu    sync.Mutex
+	kinds map[string][]string
+	block chan struct{}
+	err   error
+}
+
+func (s *recordingSink) Write(ctx context.Context, tenantID string, items []domain.Item) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kinds == nil {
+		s.kinds = make(map[string][]string)
+	}
+	for _, it := range items {
+		s.kinds[tenantID] = append(s.kinds[tenantID], it.Kind)
+	}
+	return s.err
+}
+
+func (s *recordingSink) got(tenant string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kinds[tenant]
+}
+
+type dropCounter struct {
+	mu      sync.Mutex
+	dropped map[string]int
+}
+
+func (d *dropCounter) ItemsReceived(string, string, int) {}
+
+func (d *dropCounter) ItemsDropped(_, reason string, n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dropped == nil {
+		d.dropped = make(map[string]int)
+	}
+	d.dropped[reason] += n
+}
+
+func (d *dropCounter) count(reason string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dropped[reason]
+}
+
+func routedPayload(app, env string) *domain.Payload {
+	p := &domain.Payload{
+		Logs:       []domain.LogEntry{{Message: "hi", Level: "info"}},
+		Events:     []domain.Event{{Name: "click"}},
+		Exceptions: []domain.Exception{{Type: "Error", Value: "boom"}},
+	}
+	p.Meta.App = domain.AppMeta{Name: app, Environment: env}
+	return p
+}
+
+func TestSinkRouterRoutes(t *testing.T) {
+	lokiSink, exceptions, staging := &recordingSink{}, &recordingSink{}, &recordingSink{}
+	routes, err := config.ParseSinkRoutes("kind=exception->exceptions; env=staging,app=shop|admin->staging,loki; *->loki")
+	require.NoError(t, err)
+	var usecaseRoutes []usecase.Route
+	for _, r := range routes {
+		usecaseRoutes = append(usecaseRoutes, usecase.Route{Tenants: r.Tenants, Apps: r.Apps, Environments: r.Environments, Kinds: r.Kinds, Sinks: r.Sinks})
+	}
+	router, err := usecase.NewSinkRouter(map[string]usecase.Sink{
+		"loki": lokiSink, "exceptions": exceptions, "staging": staging,
+	}, usecaseRoutes)
+	require.NoError(t, err)
+
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(router))
+	require.NoError(t, svc.Collect(context.Background(), "elven", routedPayload("shop", "prod")))
+	require.NoError(t, svc.Collect(context.Background(), "acme", routedPayload("shop", "staging")))
+	require.NoError(t, router.Close(context.Background()))
+
+	assert.Equal(t, []string{"log", "event"}, lokiSink.got("elven"))
+	assert.Equal(t, []string{"exception"}, exceptions.got("elven"))
+	assert.Equal(t, []string{"exception"}, exceptions.got("acme"))
+	assert.Equal(t, []string{"log", "event"}, staging.got("acme"))
+	assert.Equal(t, []string{"log", "event"}, lokiSink.got("acme"))
+	assert.Empty(t, staging.got("elven"))
+}
+
+func TestSinkRouterIsolatesSlowSink(t *testing.T) {
+	slow := &recordingSink{block: make(chan struct{})}
+	fast := &recordingSink{}
+	drops := &dropCounter{}
+	router, err := usecase.NewSinkRouter(map[string]usecase.Sink{"slow": slow, "fast": fast}, nil,
+		usecase.WithQueueSize(4), usecase.WithRouterRecorder(drops))
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(router))
+
+	for i := 1; i <= 5; i++ {
+		start := time.Now()
+		require.NoError(t, svc.Collect(context.Background(), "elven", routedPayload("shop", "prod")))
+		assert.Less(t, time.Since(start), 100*time.Millisecond, "collect must not wait for the slow sink")
+		require.Eventually(t, func() bool { return len(fast.got("elven")) == 3*i }, time.Second, time.Millisecond)
+	}
+	assert.Greater(t, drops.count(usecase.DropReasonQueueFull), 0)
+	assert.Greater(t, router.QueueDepth("slow"), 0)
+
+	close(slow.block)
+	require.NoError(t, router.Close(context.Background()))
+	assert.Equal(t, 0, router.QueueDepth("slow"))
+	assert.Equal(t, 15, len(slow.got("elven"))+drops.count(usecase.DropReasonQueueFull))
+}
+
+func TestSinkRouterQueueFull(t *testing.T) {
+	blocked := &recordingSink{block: make(chan struct{})}
+	defer close(blocked.block)
+	router, err := usecase.NewSinkRouter(map[string]usecase.Sink{"loki": blocked}, nil, usecase.WithQueueSize(3))
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(router))
+
+	require.NoError(t, svc.Collect(context.Background(), "elven", routedPayload("shop", "prod")))
+	err = svc.Collect(context.Background(), "elven", routedPayload("shop", "prod"))
+	assert.True(t, errors.Is(err, usecase.ErrQueueFull))
+}
+
+func TestSinkRouterSinkErrorsAreCounted(t *testing.T) {
+	failing := &recordingSink{err: errors.New("down")}
+	drops := &dropCounter{}
+	router, err := usecase.NewSinkRouter(map[string]usecase.Sink{"loki": failing},
+		[]usecase.Route{{Kinds: []string{"log"}, Sinks: []string{"loki"}}}, usecase.WithRouterRecorder(drops))
+	require.NoError(t, err)
+
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(router))
+	require.NoError(t, svc.Collect(context.Background(), "elven", routedPayload("shop", "prod")))
+	require.NoError(t, router.Close(context.Background()))
+	assert.Equal(t, 1, drops.count(usecase.DropReasonSinkError))
+	assert.Equal(t, 2, drops.count(usecase.DropReasonNoRoute))
+}
+
+func TestParseSinkRoutesErrors(t *testing.T) {
+	for _, spec := range []string{"kind=exception", "kind=trace->loki", "foo=bar->loki", "app=shop->"} {
+		_, err := config.ParseSinkRoutes(spec)
+		assert.Error(t, err, spec)
+	}
+	_, err := usecase.NewSinkRouter(map[string]usecase.Sink{"loki": &recordingSink{}},
+		[]usecase.Route{{Sinks: []string{"otlp"}}})
+	assert.Error(t, err)
+}
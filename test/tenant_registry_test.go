@@ -0,0 +1,225 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/loki"
+	"collector-fe-instrumentation/internal/adapter/tenant"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lokiReceiver records the headers of each push.
+type lokiReceiver struct {
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (r *lokiReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = append(r.headers, req.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *lokiReceiver) received() []http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]http.Header(nil), r.headers...)
+}
+
+func writeTenantsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestTenantRegistryRoutesToTenantLoki(t *testing.T) {
+	t.Setenv("TENANT_B_PASSWORD", "b-secret")
+	a, b := &lokiReceiver{}, &lokiReceiver{}
+	srvA, srvB := httptest.NewServer(a), httptest.NewServer(b)
+	defer srvA.Close()
+	defer srvB.Close()
+
+	path := writeTenantsFile(t, `{"tenants": {
+		"acme": {"loki": {"url": "`+srvA.URL+`", "auth": {"type": "bearer", "token": "a-token"}, "timeout": "5s"}},
+		"beta": {"loki": {"url": "`+srvB.URL+`", "org_id": "beta-prod", "headers": {"X-Cluster": "eu"},
+			"auth": {"type": "basic", "username": "beta", "password": "${TENANT_B_PASSWORD}"}}}
+	}}`)
+	reg, err := tenant.Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "beta"}, reg.Names())
+
+	svc := usecase.NewCollectorService(loki.NewTenantWriter(loki.RegistryResolver(reg, loki.Options{})), nil)
+	payload := func() *domain.Payload {
+		return &domain.Payload{Logs: []domain.LogEntry{{Message: "hi", Level: "info"}}}
+	}
+	require.NoError(t, svc.Collect(context.Background(), "acme", payload()))
+	require.NoError(t, svc.Collect(context.Background(), "beta", payload()))
+
+	gotA, gotB := a.received(), b.received()
+	require.Len(t, gotA, 1)
+	require.Len(t, gotB, 1)
+	assert.Equal(t, "Bearer a-token", gotA[0].Get("Authorization"))
+	assert.Equal(t, "acme", gotA[0].Get("X-Scope-OrgID"))
+	user, pass, ok := (&http.Request{Header: gotB[0]}).BasicAuth()
+	require.True(t, ok)
+	assert.Equal(t, "beta", user)
+	assert.Equal(t, "b-secret", pass)
+	assert.Equal(t, "beta-prod", gotB[0].Get("X-Scope-OrgID"))
+	assert.Equal(t, "eu", gotB[0].Get("X-Cluster"))
+
+	err = svc.Collect(context.Background(), "gamma", payload())
+	assert.True(t, errors.Is(err, domain.ErrUnknownTenant), err)
+}
+
+func TestTenantRegistryReloadSwitchesEndpoint(t *testing.T) {
+	a, b := &lokiReceiver{}, &lokiReceiver{}
+	srvA, srvB := httptest.NewServer(a), httptest.NewServer(b)
+	defer srvA.Close()
+	defer srvB.Close()
+
+	path := writeTenantsFile(t, `{"tenants": {"acme": {"loki": {"url": "`+srvA.URL+`"}}}}`)
+	reg, err := tenant.Load(path, nil)
+	require.NoError(t, err)
+	w := loki.NewTenantWriter(loki.RegistryResolver(reg, loki.Options{}))
+	streams := []domain.LokiStream{{Stream: map[string]string{"app": "t"}, Values: [][]string{{"1", "x"}}}}
+
+	require.NoError(t, w.Push(context.Background(), "acme", streams))
+	require.NoError(t, os.WriteFile(path, []byte(`{"tenants": {"acme": {"loki": {"url": "`+srvB.URL+`"}}}}`), 0o600))
+	_, err = reg.Reload()
+	require.NoError(t, err)
+	require.NoError(t, w.Push(context.Background(), "acme", streams))
+
+	assert.Len(t, a.received(), 1)
+	assert.Len(t, b.received(), 1)
+	assert.Empty(t, b.received()[0].Get("Authorization"))
+}
+
+func TestRegistryResolverDefaults(t *testing.T) {
+	path := writeTenantsFile(t, `{"tenants": {
+		"shared": {"loki": {"org_id": "shared-org"}},
+		"own":    {"loki": {"url": "https://own-loki.example.com", "timeout": "3s"}},
+		"authed": {"loki": {"url": "https://authed-loki.example.com", "auth": {"type": "bearer", "token": "t-token"}}},
+		"custom": {"loki": {"auth": {"type": "basic", "username": "u", "password": "p"}}}
+	}}`)
+	reg, err := tenant.Load(path, nil)
+	require.NoError(t, err)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{ServerName: "global-loki.internal", MinVersion: tls.VersionTLS13}}
+	resolve := loki.RegistryResolver(reg, loki.Options{
+		URL: "https://global-loki.example.com", Auth: loki.Auth{Token: "global-token"}, Timeout: 10 * time.Second, Transport: transport,
+	})
+
+	opts, ok := resolve("shared")
+	require.True(t, ok)
+	assert.Equal(t, "https://global-loki.example.com", opts.URL)
+	assert.Equal(t, "global-token", opts.Auth.Token, "the global token goes with the global URL")
+	assert.Equal(t, "shared-org", opts.OrgID)
+	assert.Equal(t, 10*time.Second, opts.Timeout)
+	assert.Same(t, transport, opts.Transport)
+
+	opts, ok = resolve("own")
+	require.True(t, ok)
+	assert.Equal(t, "https://own-loki.example.com", opts.URL)
+	assert.Equal(t, loki.Auth{}, opts.Auth, "the global token must not leak to a tenant's own endpoint")
+	assert.Equal(t, 3*time.Second, opts.Timeout)
+	own, ok := opts.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.NotSame(t, transport, own, "the global TLS settings must not apply to a tenant's own endpoint")
+	assert.Empty(t, own.TLSClientConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), own.TLSClientConfig.MinVersion)
+
+	opts, _ = resolve("authed")
+	assert.Same(t, own, opts.Transport, "tenant endpoints share one pool")
+	assert.Equal(t, loki.Auth{Type: tenant.AuthBearer, Token: "t-token"}, opts.Auth)
+
+	opts, _ = resolve("custom")
+	assert.Equal(t, "https://global-loki.example.com", opts.URL)
+	assert.Equal(t, loki.Auth{Type: tenant.AuthBasic, Username: "u", Password: "p"}, opts.Auth)
+
+	_, ok = resolve("unknown")
+	assert.False(t, ok)
+}
+
+func TestTenantRegistryRequireLokiURL(t *testing.T) {
+	path := writeTenantsFile(t, `{"tenants": {"acme": {"loki": {"url": "https://loki.example.com"}}, "beta": {}}}`)
+	_, err := tenant.Load(path, nil, tenant.RequireLokiURL())
+	assert.ErrorContains(t, err, `tenant "beta": loki.url is required`)
+
+	_, err = tenant.Load(path, nil)
+	assert.NoError(t, err, "without the option beta falls back to LOKI_URL")
+}
+
+func TestTenantRegistryExpandsOnlyBracedEnv(t *testing.T) {
+	t.Setenv("ACME_LOKI_USER", "acme")
+	path := writeTenantsFile(t, `{"tenants": {"acme": {"loki": {
+		"auth": {"type": "basic", "username": "${ACME_LOKI_USER}", "password": "pa$$w0rd$HOME"},
+		"headers": {"X-Cost": "$5"}
+	}}}}`)
+	reg, err := tenant.Load(path, nil)
+	require.NoError(t, err)
+	acme, ok := reg.Lookup("acme")
+	require.True(t, ok)
+	assert.Equal(t, "acme", acme.Loki.Auth.Username)
+	assert.Equal(t, "pa$$w0rd$HOME", acme.Loki.Auth.Password, "a bare $ is a literal")
+	assert.Equal(t, "$5", acme.Loki.Headers["X-Cost"])
+
+	_, err = tenant.Load(writeTenantsFile(t, `{"tenants": {"acme": {"loki": {
+		"auth": {"type": "bearer", "token": "${TENANT_TEST_UNSET_TOKEN}"}
+	}}}}`), nil)
+	assert.ErrorContains(t, err, "TENANT_TEST_UNSET_TOKEN is not set")
+}
+
+func TestTenantRegistryInvalidFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"not json":        `tenants: []`,
+		"missing tenants": `{}`,
+		"unknown field":   `{"tenants": {"acme": {"loki": {"uri": "http://loki"}}}}`,
+		"bad auth type":   `{"tenants": {"acme": {"loki": {"auth": {"type": "digest"}}}}}`,
+		"bearer no token": `{"tenants": {"acme": {"loki": {"auth": {"type": "bearer"}}}}}`,
+		"bad url":         `{"tenants": {"acme": {"loki": {"url": "loki:3100"}}}}`,
+		"bad timeout":     `{"tenants": {"acme": {"loki": {"timeout": "soon"}}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tenant.Load(writeTenantsFile(t, content), nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRouterRejectsUnknownTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	reg, err := tenant.Load(writeTenantsFile(t, `{"tenants": {"elven": {}}}`), nil)
+	require.NoError(t, err)
+
+	router := httpadapter.Router(cfg, usecase.NewCollectorService(noopLoki{}, nil), httpadapter.WithTenantRegistry(reg))
+	token := generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"})
+	collect := func(tenantID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/collect/"+tenantID, strings.NewReader(minimalCollectPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, collect("elven"))
+	assert.Equal(t, http.StatusForbidden, collect("unknown"))
+}
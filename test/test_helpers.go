@@ -6,6 +6,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// minimalCollectPayload is the smallest Faro payload the collector accepts.
+const minimalCollectPayload = `{"meta":{"app":{"name":"t"},"browser":{},"view":{},"page":{},"session":{},"sdk":{},"user":{}},"logs":[{"message":"hi","level":"info"}]}`
+
 func init() {
 	_ = os.Setenv("SECRET_KEY", "a-very-secure-key-with-at-least-64-characters-for-jwt-validation-test")
 	_ = os.Setenv("JWT_ISSUER", "trusted-issuer")
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTTimeClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	base := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name           string
+		requireExp     bool
+		maxAge         time.Duration
+		claims         jwt.MapClaims
+		expectedStatus int
+	}{
+		{
+			name:           "Not yet valid",
+			claims:         base(jwt.MapClaims{"nbf": now.Add(10 * time.Minute).Unix()}),
+			expectedStatus: 401,
+		},
+		{
+			name:           "nbf within leeway",
+			claims:         base(jwt.MapClaims{"nbf": now.Add(10 * time.Second).Unix()}),
+			expectedStatus: 200,
+		},
+		{
+			name:           "Issued in the future",
+			claims:         base(jwt.MapClaims{"iat": now.Add(10 * time.Minute).Unix()}),
+			expectedStatus: 401,
+		},
+		{
+			name:           "Expired within leeway",
+			claims:         base(jwt.MapClaims{"exp": now.Add(-10 * time.Second).Unix()}),
+			expectedStatus: 200,
+		},
+		{
+			name:           "Missing exp allowed by default",
+			claims:         base(nil),
+			expectedStatus: 200,
+		},
+		{
+			name:           "Missing exp when required",
+			requireExp:     true,
+			claims:         base(nil),
+			expectedStatus: 401,
+		},
+		{
+			name:           "Older than max age",
+			maxAge:         time.Hour,
+			claims:         base(jwt.MapClaims{"iat": now.Add(-2 * time.Hour).Unix()}),
+			expectedStatus: 401,
+		},
+		{
+			name:           "Missing iat with max age",
+			maxAge:         time.Hour,
+			claims:         base(nil),
+			expectedStatus: 401,
+		},
+		{
+			name:           "Within max age",
+			maxAge:         time.Hour,
+			claims:         base(jwt.MapClaims{"iat": now.Add(-30 * time.Minute).Unix()}),
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.JWTLeeway = 30 * time.Second
+			cfg.JWTRequireExp = tt.requireExp
+			cfg.JWTMaxTokenAge = tt.maxAge
+			router := gin.New()
+			router.Use(httpadapter.JWTAuth(cfg))
+			router.GET("/protected/:token", func(c *gin.Context) {
+				c.JSON(200, gin.H{"message": "Success"})
+			})
+			req := httptest.NewRequest("GET", "/protected/"+generateJWT(tt.claims), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
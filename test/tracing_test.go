@@ -0,0 +1,286 @@
+package test
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	httpadapter "collector-fe-instrumentation/internal/adapter/http"
+	"collector-fe-instrumentation/internal/adapter/loki"
+	"collector-fe-instrumentation/internal/adapter/otlp"
+	"collector-fe-instrumentation/internal/config"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/tracing"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// exportedSpan is the part of an OTLP span the tests look at.
+type exportedSpan struct {
+	traceID, spanID, parentID string
+}
+
+// traceReceiver is an OTLP/HTTP traces endpoint that decodes the spans it receives, by name.
+type traceReceiver struct {
+	mu    sync.Mutex
+	paths []string
+	spans map[string]exportedSpan
+}
+
+func (r *traceReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, req.URL.Path)
+	if r.spans == nil {
+		r.spans = make(map[string]exportedSpan)
+	}
+	forEachField(body, func(_ protowire.Number, rs []byte) {
+		forEachField(rs, func(n protowire.Number, ss []byte) {
+			if n != 2 {
+				return
+			}
+			forEachField(ss, func(n protowire.Number, span []byte) {
+				if n != 2 {
+					return
+				}
+				var s exportedSpan
+				var name string
+				forEachField(span, func(n protowire.Number, v []byte) {
+					switch n {
+					case 1:
+						s.traceID = hex.EncodeToString(v)
+					case 2:
+						s.spanID = hex.EncodeToString(v)
+					case 4:
+						s.parentID = hex.EncodeToString(v)
+					case 5:
+						name = string(v)
+					}
+				})
+				r.spans[name] = s
+			})
+		})
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *traceReceiver) span(name string) (exportedSpan, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.spans[name]
+	return s, ok
+}
+
+// traceparentLoki accepts pushes and keeps the last traceparent header.
+func traceparentLoki(t *testing.T) (*httptest.Server, func() string) {
+	var mu sync.Mutex
+	var last string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		last = r.Header.Get(tracing.TraceparentHeader)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+func tracedCollect(t *testing.T, tracer *tracing.Tracer, sink usecase.Sink, traceparent string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := httpadapter.Router(testConfig(t), usecase.NewCollectorService(nil, nil, usecase.WithSink(sink)), httpadapter.WithTracer(tracer))
+	req := httptest.NewRequest(http.MethodPost, "/collect/elven", strings.NewReader(minimalCollectPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+generateJWT(jwt.MapClaims{"role": "admin", "iss": "trusted-issuer"}))
+	if traceparent != "" {
+		req.Header.Set(tracing.TraceparentHeader, traceparent)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestTracingSpansAndPropagationToLoki(t *testing.T) {
+	receiver := &traceReceiver{}
+	otlpSrv := httptest.NewServer(receiver)
+	defer otlpSrv.Close()
+	lokiSrv, lokiTraceparent := traceparentLoki(t)
+
+	tracer := tracing.NewTracer(otlp.NewTraceExporter(otlpSrv.URL, "collector-test", nil, time.Second), tracing.Options{SampleRatio: 1})
+	sink := usecase.NewLokiSink(loki.NewClient(lokiSrv.URL, "token", time.Second))
+	require.Equal(t, http.StatusOK, tracedCollect(t, tracer, sink, incomingTraceparent))
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Equal(t, []string{"/v1/traces"}, receiver.paths)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	root, ok := receiver.span("POST /collect/:tenant")
+	require.True(t, ok)
+	assert.Equal(t, traceID, root.traceID)
+	assert.Equal(t, "00f067aa0ba902b7", root.parentID, "continues the incoming trace")
+
+	for _, name := range []string{"auth.validate", "payload.decode", "collector.payload_to_items", "sink.write"} {
+		s, ok := receiver.span(name)
+		require.True(t, ok, name)
+		assert.Equal(t, traceID, s.traceID, name)
+		assert.Equal(t, root.spanID, s.parentID, name)
+	}
+	sinkWrite, _ := receiver.span("sink.write")
+	transform, ok := receiver.span("loki.payload_to_streams")
+	require.True(t, ok)
+	assert.Equal(t, sinkWrite.spanID, transform.parentID)
+	push, ok := receiver.span("loki.push")
+	require.True(t, ok)
+	assert.Equal(t, sinkWrite.spanID, push.parentID)
+
+	// Loki sees the push span as the parent of its own work.
+	assert.Equal(t, "00-"+traceID+"-"+push.spanID+"-01", lokiTraceparent())
+}
+
+// memoryExporter keeps exported spans in memory.
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *memoryExporter) ExportSpans(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTracingUnsampledStillPropagates(t *testing.T) {
+	lokiSrv, lokiTraceparent := traceparentLoki(t)
+	exporter := &memoryExporter{}
+	tracer := tracing.NewTracer(exporter, tracing.Options{SampleRatio: 0})
+	sink := usecase.NewLokiSink(loki.NewClient(lokiSrv.URL, "token", time.Second))
+
+	require.Equal(t, http.StatusOK, tracedCollect(t, tracer, sink, ""))
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Empty(t, exporter.spans)
+	sc, ok := tracing.ParseTraceparent(lokiTraceparent())
+	require.True(t, ok)
+	assert.False(t, sc.Sampled)
+
+	// A client claiming a sampled parent cannot force recording: the ratio decides, and the
+	// trace ID is still carried on.
+	require.Equal(t, http.StatusOK, tracedCollect(t, tracer, sink, incomingTraceparent))
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Empty(t, exporter.spans)
+	sc, ok = tracing.ParseTraceparent(lokiTraceparent())
+	require.True(t, ok)
+	assert.False(t, sc.Sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(sc.TraceID[:]))
+}
+
+func TestTracingPropagatesTracestate(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	tracer := tracing.NewTracer(&memoryExporter{}, tracing.Options{SampleRatio: 1})
+
+	in := http.Header{}
+	in.Set(tracing.TraceparentHeader, incomingTraceparent)
+	in.Add(tracing.TracestateHeader, "congo=t61rcWkgMzE")
+	in.Add(tracing.TracestateHeader, "rojo=00f067aa0ba902b7")
+	remote, ok := tracing.Extract(in)
+	require.True(t, ok)
+	ctx, span := tracer.Start(context.Background(), "POST /collect/:tenant", tracing.KindServer, remote)
+	defer span.End()
+
+	streams := []domain.LokiStream{{Stream: map[string]string{"app": "t"}, Values: [][]string{{"1", "x"}}}}
+	require.NoError(t, loki.NewClient(srv.URL, "token", time.Second).Push(ctx, "elven", streams))
+	assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", got.Get(tracing.TracestateHeader))
+
+	in.Set(tracing.TracestateHeader, strings.Repeat("k=v,", 200))
+	remote, ok = tracing.Extract(in)
+	require.True(t, ok)
+	assert.Empty(t, remote.TraceState, "over-long tracestate is dropped")
+}
+
+func TestTracingRemoteTraceIDDoesNotDecideSampling(t *testing.T) {
+	tracer := tracing.NewTracer(&memoryExporter{}, tracing.Options{SampleRatio: 0.01})
+	// The lowest possible trace ID would always fall under the ratio if it were sampled on.
+	remote, ok := tracing.ParseTraceparent("00-00000000000000000000000000000001-00f067aa0ba902b7-01")
+	require.True(t, ok)
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		_, span := tracer.Start(context.Background(), "POST /collect/:tenant", tracing.KindServer, remote)
+		if span.SpanContext().Sampled {
+			sampled++
+		}
+		assert.Equal(t, remote.TraceID, span.SpanContext().TraceID)
+	}
+	assert.Less(t, sampled, 100, "about 1 in 100 requests is sampled, whatever trace ID the client picks")
+}
+
+func TestTracingRejectsNaNSampleRatio(t *testing.T) {
+	t.Setenv("TRACING_OTLP_ENDPOINT", "http://otel-collector:4318")
+	t.Setenv("TRACING_SAMPLE_RATIO", "NaN")
+	assert.ErrorIs(t, config.Load().Validate(), config.ErrInvalidTracing)
+
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.5")
+	assert.NoError(t, config.Load().Validate())
+}
+
+func TestTracingFollowsQueuedWrites(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := tracing.NewTracer(exporter, tracing.Options{SampleRatio: 1})
+	router, err := usecase.NewSinkRouter(map[string]usecase.Sink{"a": &recordingSink{}}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, tracedCollect(t, tracer, router, incomingTraceparent))
+	require.NoError(t, router.Close(context.Background()))
+	require.NoError(t, tracer.Flush(context.Background()))
+
+	var queued *tracing.SpanData
+	for i := range exporter.spans {
+		if exporter.spans[i].Name == "sink.queue_write" {
+			queued = &exporter.spans[i]
+		}
+	}
+	require.NotNil(t, queued)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(queued.TraceID[:]))
+	assert.Contains(t, queued.Attributes, tracing.String("sink", "a"))
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := tracing.ParseTraceparent(incomingTraceparent)
+	require.True(t, ok)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, incomingTraceparent, sc.Traceparent())
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, ok := tracing.ParseTraceparent(bad)
+		assert.False(t, ok, bad)
+	}
+}
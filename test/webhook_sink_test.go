@@ -0,0 +1,176 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"collector-fe-instrumentation/internal/adapter/webhook"
+	"collector-fe-instrumentation/internal/domain"
+	"collector-fe-instrumentation/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	b, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, b)
+	r.headers = append(r.headers, req.Header.Clone())
+}
+
+func (r *webhookReceiver) alerts(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []map[string]interface{}
+	for _, b := range r.bodies {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &m), string(b))
+		out = append(out, m)
+	}
+	return out
+}
+
+func exceptionPayload(value string) *domain.Payload {
+	p := &domain.Payload{
+		Logs: []domain.LogEntry{{Message: "ignored", Level: "error"}},
+		Exceptions: []domain.Exception{{Type: "TypeError", Value: value, Stacktrace: domain.Stacktrace{Frames: []domain.StackFrame{
+			{Filename: "app.js", Function: "checkout", Lineno: 10, Colno: 5},
+		}}}},
+	}
+	p.Meta.App = domain.AppMeta{Name: "shop", Environment: "prod"}
+	p.Meta.Page.URL = "https://shop.example.com/cart"
+	return p
+}
+
+func TestWebhookDedupAndSpike(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	sink, err := webhook.New(webhook.Options{URL: srv.URL, Window: time.Hour, SpikeThreshold: 3, Secret: "s3cret"})
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(sink))
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, svc.Collect(context.Background(), "elven", exceptionPayload(`x is "undefined"`)))
+	}
+	require.NoError(t, svc.Collect(context.Background(), "elven", exceptionPayload("y is undefined")))
+
+	alerts := receiver.alerts(t)
+	require.Len(t, alerts, 3)
+	assert.Equal(t, "new", alerts[0]["reason"])
+	assert.Equal(t, `x is "undefined"`, alerts[0]["value"])
+	assert.Equal(t, "shop", alerts[0]["app"])
+	assert.Equal(t, "https://shop.example.com/cart", alerts[0]["page_url"])
+	assert.Equal(t, "spike", alerts[1]["reason"])
+	assert.Equal(t, float64(3), alerts[1]["count"])
+	assert.Equal(t, alerts[0]["fingerprint"], alerts[1]["fingerprint"])
+	assert.Equal(t, "new", alerts[2]["reason"])
+	assert.NotEqual(t, alerts[0]["fingerprint"], alerts[2]["fingerprint"])
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	h := receiver.headers[0]
+	assert.Equal(t, "sha256="+webhook.Sign("s3cret", h.Get(webhook.HeaderTimestamp), receiver.bodies[0]), h.Get(webhook.HeaderSignature))
+}
+
+func TestWebhookSlackFormatAndRetry(t *testing.T) {
+	receiver := &webhookReceiver{failures: 1}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	tmpl, err := webhook.ParseTemplate(webhook.FormatSlack, "")
+	require.NoError(t, err)
+	sink, err := webhook.New(webhook.Options{URL: srv.URL, Template: tmpl, MaxRetries: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, usecase.NewCollectorService(nil, nil, usecase.WithSink(sink)).
+		Collect(context.Background(), "elven", exceptionPayload("boom")))
+	alerts := receiver.alerts(t)
+	require.Len(t, alerts, 1)
+	assert.Contains(t, alerts[0]["text"], "New exception in *shop* (prod, tenant elven): TypeError: boom")
+}
+
+func TestWebhookCustomTemplate(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	tmpl, err := webhook.ParseTemplate("", `{"summary":{{json (printf "%s/%s" .Tenant .Type)}}}`)
+	require.NoError(t, err)
+	sink, err := webhook.New(webhook.Options{URL: srv.URL, Template: tmpl})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), "acme", []domain.Item{{
+		Kind: domain.KindException, Fields: map[string]interface{}{"exception_type": "RangeError"},
+	}}))
+	assert.Equal(t, []map[string]interface{}{{"summary": "acme/RangeError"}}, receiver.alerts(t))
+
+	_, err = webhook.ParseTemplate("teams", "")
+	assert.Error(t, err)
+}
+
+func TestWebhookFingerprintNormalisesMessage(t *testing.T) {
+	fp := func(msg string) string { return webhook.Fingerprint("elven", "shop", "Error", msg, "app.js:10:5") }
+	assert.Equal(t, fp("order 123 not found"), fp("order 456 not found"))
+	assert.Equal(t, fp("GET https://api.example.com/orders/1?x=2 failed"), fp("GET https://api.example.com/orders/9 failed"))
+	assert.Equal(t, fp("session 0b8f3c2e-1d4a-4b6f-9c2d-7e1f2a3b4c5d expired"), fp("session 9d1e2f3a-4b5c-4d6e-8f7a-1b2c3d4e5f6a expired"))
+	assert.Equal(t, fp("object at 0x7ffd3 freed, chunk a3f9e1"), fp("object at 0x1a2b freed, chunk 77c0d2"))
+	assert.NotEqual(t, fp("order 123 not found"), fp("user 123 not found"))
+	assert.Equal(t, "order <n> at <url> (<uuid>)", webhook.NormalizeMessage("order 42 at http://x.io/a (0b8f3c2e-1d4a-4b6f-9c2d-7e1f2a3b4c5d)"))
+}
+
+func TestWebhookMaxAlertsPerMinute(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	sink, err := webhook.New(webhook.Options{URL: srv.URL, MaxAlertsPerMinute: 2})
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(sink))
+	for _, typ := range []string{"TypeError", "RangeError", "SyntaxError", "ReferenceError"} {
+		p := exceptionPayload("boom")
+		p.Exceptions[0].Type = typ
+		require.NoError(t, svc.Collect(context.Background(), "elven", p))
+	}
+	assert.Len(t, receiver.alerts(t), 2, "distinct fingerprints beyond the limit are dropped")
+}
+
+func TestWebhookFailedAlertIsRaisedAgain(t *testing.T) {
+	receiver := &webhookReceiver{failures: 1}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	sink, err := webhook.New(webhook.Options{URL: srv.URL, Window: time.Hour})
+	require.NoError(t, err)
+	svc := usecase.NewCollectorService(nil, nil, usecase.WithSink(sink))
+
+	// The first alert fails without retries; the fingerprint must not count as alerted.
+	_ = svc.Collect(context.Background(), "elven", exceptionPayload("boom"))
+	require.NoError(t, svc.Collect(context.Background(), "elven", exceptionPayload("boom")))
+	require.NoError(t, svc.Collect(context.Background(), "elven", exceptionPayload("boom")))
+
+	alerts := receiver.alerts(t)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "new", alerts[0]["reason"])
+}